@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/segmentio/golines/shorten"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer reports the regions of each file that golines would change, with suggested
+// fixes that apply the changes. Its options can be set via its flags.
+var Analyzer = &analysis.Analyzer{
+	Name: "golines",
+	Doc:  "reports long lines that can be shortened by golines",
+	URL:  "https://github.com/segmentio/golines",
+	Run:  run,
+}
+
+var (
+	maxLen          int
+	tabLen          int
+	shortenComments bool
+	reformatTags    bool
+	chainSplitDots  bool
+	splitStrings    bool
+	hoistIfInit     bool
+	baseFormatter   string
+	localPrefixes   string
+)
+
+func init() {
+	Analyzer.Flags.IntVar(&maxLen, "max-len", 100, "Target maximum line length")
+	Analyzer.Flags.IntVar(&tabLen, "tab-len", 4, "Length of a tab")
+	Analyzer.Flags.BoolVar(
+		&shortenComments,
+		"shorten-comments",
+		false,
+		"Shorten single-line comments",
+	)
+	Analyzer.Flags.BoolVar(&reformatTags, "reformat-tags", true, "Reformat struct tags")
+	Analyzer.Flags.BoolVar(
+		&chainSplitDots,
+		"chain-split-dots",
+		true,
+		"Split chained methods on the dots as opposed to the arguments",
+	)
+	Analyzer.Flags.BoolVar(
+		&splitStrings,
+		"split-strings",
+		false,
+		"Split long string literals into concatenations",
+	)
+	Analyzer.Flags.BoolVar(
+		&hoistIfInit,
+		"hoist-if-init",
+		false,
+		"Move the init statements of long if statements before them when possible",
+	)
+	Analyzer.Flags.StringVar(&baseFormatter, "base-formatter", "", "Base formatter to use")
+	Analyzer.Flags.StringVar(
+		&localPrefixes,
+		"local-prefixes",
+		"",
+		"Comma-separated import path prefixes that goimports puts after the third-party imports",
+	)
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	shortener := shorten.NewShortener(
+		shorten.ShortenerConfig{
+			MaxLen:           maxLen,
+			TabLen:           tabLen,
+			ShortenComments:  shortenComments,
+			ReformatTags:     reformatTags,
+			BaseFormatterCmd: baseFormatter,
+			LocalPrefixes:    localPrefixes,
+			ChainSplitDots:   chainSplitDots,
+			SplitStrings:     splitStrings,
+			HoistIfInit:      hoistIfInit,
+		},
+	)
+
+	for _, file := range pass.Files {
+		if ast.IsGenerated(file) {
+			continue
+		}
+
+		tokenFile := pass.Fset.File(file.Pos())
+		if tokenFile == nil || !strings.HasSuffix(tokenFile.Name(), ".go") {
+			continue
+		}
+
+		contents, err := pass.ReadFile(tokenFile.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		edits, err := shortener.WithFilename(tokenFile.Name()).ShortenEdits(contents)
+		if err != nil {
+			return nil, fmt.Errorf("error shortening %s: %+v", tokenFile.Name(), err)
+		}
+
+		longLines := shortener.LongLines(contents)
+
+		for _, edit := range edits {
+			pos := tokenFile.Pos(edit.Offset)
+			end := tokenFile.Pos(edit.Offset + edit.Length)
+
+			pass.Report(
+				analysis.Diagnostic{
+					Pos: pos,
+					End: end,
+					Message: diagnosticMessage(
+						longLines,
+						tokenFile.Line(pos),
+						tokenFile.Line(end),
+					),
+					SuggestedFixes: []analysis.SuggestedFix{
+						{
+							Message: "Shorten with golines",
+							TextEdits: []analysis.TextEdit{
+								{Pos: pos, End: end, NewText: []byte(edit.NewText)},
+							},
+						},
+					},
+				},
+			)
+		}
+	}
+
+	return nil, nil
+}
+
+// diagnosticMessage returns the message for an edit that covers the provided lines. The
+// message describes the first long line in the range, if there is one; otherwise, the
+// edit only contains changes from the base formatter or struct tag reformatting.
+func diagnosticMessage(longLines []shorten.LongLine, startLine int, endLine int) string {
+	for _, longLine := range longLines {
+		if longLine.Line >= startLine && longLine.Line <= endLine {
+			return fmt.Sprintf(
+				"line is %d characters long, which exceeds the maximum of %d",
+				longLine.Length,
+				longLine.MaxLen,
+			)
+		}
+	}
+
+	return "file is not formatted with golines"
+}
@@ -0,0 +1,16 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	err := Analyzer.Flags.Set("base-formatter", "gofmt")
+	if err != nil {
+		t.Fatal("Unexpected error setting flag", err)
+	}
+
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), Analyzer, "a")
+}
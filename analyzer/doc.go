@@ -0,0 +1,11 @@
+/*
+Package analyzer provides a go/analysis Analyzer that reports the regions of each file that
+golines would change, along with suggested fixes that apply the changes. It can be run via
+the golines-lint command, used as a go vet tool, or integrated into other drivers like
+golangci-lint:
+
+	go vet -vettool=$(which golines-lint) -golines.max-len=120 ./...
+
+The shortener options can be set via the analyzer's flags (max-len, tab-len, etc.).
+*/
+package analyzer // import "github.com/segmentio/golines/analyzer"
@@ -0,0 +1,11 @@
+package a
+
+import "fmt"
+
+func short() {
+	fmt.Println("short")
+}
+
+func long() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5") // want `line is \d+ characters long, which exceeds the maximum of 100`
+}
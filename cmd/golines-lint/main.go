@@ -0,0 +1,14 @@
+// The golines-lint command reports the lines that golines would shorten. It can be run
+// directly on packages or used as a go vet tool:
+//
+//	go vet -vettool=$(which golines-lint) ./...
+package main
+
+import (
+	"github.com/segmentio/golines/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/segmentio/golines/shorten"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the config files that are searched for, in order of precedence, in the
+// directory of each processed path and in all of its parents.
+var configFileNames = []string{
+	".golines.yaml",
+	".golines.yml",
+	".golines.toml",
+}
+
+// fileConfig is the contents of a golines config file. Apart from root, each key has the
+// same name as the equivalent command-line flag. Keys that are missing from the file are left
+// nil so that the flag values (or their defaults) are used instead.
+type fileConfig struct {
+	// Whether to stop looking for config files in parent directories
+	Root bool `yaml:"root" toml:"root"`
+
+	MaxLen          *int     `yaml:"max-len"          toml:"max-len"`
+	TabLen          *int     `yaml:"tab-len"          toml:"tab-len"`
+	ShortenComments *bool    `yaml:"shorten-comments" toml:"shorten-comments"`
+	ReformatTags    *bool    `yaml:"reformat-tags"    toml:"reformat-tags"`
+	IgnoreGenerated *bool    `yaml:"ignore-generated" toml:"ignore-generated"`
+	IgnoredDirs     []string `yaml:"ignored-dirs"     toml:"ignored-dirs"`
+	ChainSplitDots  *bool    `yaml:"chain-split-dots" toml:"chain-split-dots"`
+	SplitStrings    *bool    `yaml:"split-strings"    toml:"split-strings"`
+	HoistIfInit     *bool    `yaml:"hoist-if-init"    toml:"hoist-if-init"`
+
+	// Base formatter settings
+	BaseFormatter        *string        `yaml:"base-formatter"         toml:"base-formatter"`
+	BaseFormatterTimeout *time.Duration `yaml:"base-formatter-timeout" toml:"base-formatter-timeout"`
+	LocalPrefixes        *string        `yaml:"local-prefixes"         toml:"local-prefixes"`
+
+	GofumptExtra       *bool   `yaml:"gofumpt-extra"        toml:"gofumpt-extra"`
+	GofumptLangVersion *string `yaml:"gofumpt-lang-version" toml:"gofumpt-lang-version"`
+	GofumptModulePath  *string `yaml:"gofumpt-module-path"  toml:"gofumpt-module-path"`
+}
+
+// runConfig stores the effective settings used to process a path.
+type runConfig struct {
+	shortenerConfig shorten.ShortenerConfig
+	ignoredDirs     []string
+}
+
+// flagConfig generates a runConfig from the values of the command-line flags.
+func flagConfig() runConfig {
+	return runConfig{
+		shortenerConfig: shorten.ShortenerConfig{
+			MaxLen:               *maxLen,
+			TabLen:               *tabLen,
+			KeepAnnotations:      *keepAnnotations,
+			ShortenComments:      *shortenComments,
+			ReformatTags:         *reformatTags,
+			IgnoreGenerated:      *ignoreGenerated,
+			DotFile:              *dotFile,
+			BaseFormatterCmd:     *baseFormatterCmd,
+			BaseFormatterTimeout: *baseFormatterTimeout,
+			LocalPrefixes:        *localPrefixes,
+			ChainSplitDots:       *chainSplitDots,
+			SplitStrings:         *splitStrings,
+			HoistIfInit:          *hoistIfInit,
+			GofumptLangVersion:   *gofumptLangVersion,
+			GofumptModulePath:    *gofumptModulePath,
+			GofumptExtraRules:    *gofumptExtra,
+		},
+		ignoredDirs: *ignoredDirs,
+	}
+}
+
+// configResolver determines the effective settings for each processed file. Config files
+// can be nested, with a file in a subdirectory overriding a subset of the settings from
+// the config files in its parent directories. The parsed config files and the shorteners
+// created from them are cached so that they can be reused across files.
+type configResolver struct {
+	files      map[string]*fileConfig
+	dirConfigs map[string][]*fileConfig
+	shorteners map[shorten.ShortenerConfig]*shorten.Shortener
+}
+
+// newConfigResolver creates a new, empty configResolver.
+func newConfigResolver() *configResolver {
+	return &configResolver{
+		files:      map[string]*fileConfig{},
+		dirConfigs: map[string][]*fileConfig{},
+		shorteners: map[shorten.ShortenerConfig]*shorten.Shortener{},
+	}
+}
+
+// resolve returns the effective settings for the provided path. These are the
+// command-line flag values, overlaid with the values from each config file that applies
+// to the path, from the outermost directory to the innermost one. Flags that were
+// explicitly set by the user always take precedence over config files.
+func (r *configResolver) resolve(path string) (runConfig, error) {
+	config := flagConfig()
+
+	var fileConfs []*fileConfig
+
+	if *configFile != "" {
+		fileConf, err := r.loadCached(*configFile)
+		if err != nil {
+			return config, err
+		}
+		fileConfs = []*fileConfig{fileConf}
+	} else {
+		dir, err := filepath.Abs(path)
+		if err != nil {
+			return config, err
+		}
+
+		info, err := os.Stat(dir)
+		if err != nil {
+			return config, err
+		}
+		if !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+
+		fileConfs, err = r.dirConfig(dir)
+		if err != nil {
+			return config, err
+		}
+	}
+
+	for _, fileConf := range fileConfs {
+		fileConf.apply(&config)
+	}
+
+	return config, nil
+}
+
+// shortener returns a Shortener for the provided settings, reusing an existing one if
+// another file has already been processed with the same settings.
+func (r *configResolver) shortener(config runConfig) *shorten.Shortener {
+	shortener, ok := r.shorteners[config.shortenerConfig]
+	if !ok {
+		shortener = shorten.NewShortener(config.shortenerConfig)
+		r.shorteners[config.shortenerConfig] = shortener
+	}
+
+	return shortener
+}
+
+// dirConfig returns the config files that apply to the provided (absolute) directory,
+// ordered from the outermost directory to the innermost one. The search stops at the
+// filesystem root or at a config file with the root key set.
+func (r *configResolver) dirConfig(dir string) ([]*fileConfig, error) {
+	if fileConfs, ok := r.dirConfigs[dir]; ok {
+		return fileConfs, nil
+	}
+
+	fileConfs := []*fileConfig{}
+
+	configPath, err := findConfigFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileConf *fileConfig
+	if configPath != "" {
+		fileConf, err = r.loadCached(configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parent := filepath.Dir(dir)
+	if parent != dir && (fileConf == nil || !fileConf.Root) {
+		parentConfs, err := r.dirConfig(parent)
+		if err != nil {
+			return nil, err
+		}
+		fileConfs = append(fileConfs, parentConfs...)
+	}
+
+	if fileConf != nil {
+		fileConfs = append(fileConfs, fileConf)
+	}
+
+	r.dirConfigs[dir] = fileConfs
+	return fileConfs, nil
+}
+
+// loadCached parses the config file at the provided path, or returns the cached result if
+// it's already been parsed.
+func (r *configResolver) loadCached(configPath string) (*fileConfig, error) {
+	if fileConf, ok := r.files[configPath]; ok {
+		return fileConf, nil
+	}
+
+	log.Debugf("loading config file %s", configPath)
+
+	fileConf, err := loadConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.files[configPath] = fileConf
+	return fileConf, nil
+}
+
+// findConfigFile looks for a config file in the provided directory. It returns the path of
+// the config file, or an empty string if there isn't one.
+func findConfigFile(dir string) (string, error) {
+	for _, name := range configFileNames {
+		configPath := filepath.Join(dir, name)
+
+		switch info, err := os.Stat(configPath); {
+		case err == nil && !info.IsDir():
+			return configPath, nil
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return "", err
+		}
+	}
+
+	return "", nil
+}
+
+// loadConfigFile parses the config file at the provided path. The format is determined from
+// the file extension. Unknown keys are treated as errors so that typos don't go unnoticed.
+func loadConfigFile(path string) (*fileConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &fileConfig{}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(contents))
+		decoder.KnownFields(true)
+
+		// An empty file is valid and just doesn't override anything
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(contents), config)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			keys := []string{}
+			for _, key := range undecoded {
+				keys = append(keys, key.String())
+			}
+			return nil, fmt.Errorf(
+				"unknown keys in config file %s: %s",
+				path,
+				strings.Join(keys, ", "),
+			)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", ext)
+	}
+
+	return config, nil
+}
+
+// apply overlays the values that are set in the config file onto the provided runConfig,
+// skipping any settings whose flags were explicitly set by the user.
+func (c *fileConfig) apply(config *runConfig) {
+	if c.MaxLen != nil && !flagsSetByUser["max-len"] {
+		config.shortenerConfig.MaxLen = *c.MaxLen
+	}
+	if c.TabLen != nil && !flagsSetByUser["tab-len"] {
+		config.shortenerConfig.TabLen = *c.TabLen
+	}
+	if c.ShortenComments != nil && !flagsSetByUser["shorten-comments"] {
+		config.shortenerConfig.ShortenComments = *c.ShortenComments
+	}
+	if c.ReformatTags != nil && !flagsSetByUser["reformat-tags"] {
+		config.shortenerConfig.ReformatTags = *c.ReformatTags
+	}
+	if c.IgnoreGenerated != nil && !flagsSetByUser["ignore-generated"] {
+		config.shortenerConfig.IgnoreGenerated = *c.IgnoreGenerated
+	}
+	if c.IgnoredDirs != nil && !flagsSetByUser["ignored-dirs"] {
+		config.ignoredDirs = c.IgnoredDirs
+	}
+	if c.BaseFormatter != nil && !flagsSetByUser["base-formatter"] {
+		config.shortenerConfig.BaseFormatterCmd = *c.BaseFormatter
+	}
+	if c.BaseFormatterTimeout != nil && !flagsSetByUser["base-formatter-timeout"] {
+		config.shortenerConfig.BaseFormatterTimeout = *c.BaseFormatterTimeout
+	}
+	if c.LocalPrefixes != nil && !flagsSetByUser["local-prefixes"] {
+		config.shortenerConfig.LocalPrefixes = *c.LocalPrefixes
+	}
+	if c.ChainSplitDots != nil && !flagsSetByUser["chain-split-dots"] {
+		config.shortenerConfig.ChainSplitDots = *c.ChainSplitDots
+	}
+	if c.SplitStrings != nil && !flagsSetByUser["split-strings"] {
+		config.shortenerConfig.SplitStrings = *c.SplitStrings
+	}
+	if c.HoistIfInit != nil && !flagsSetByUser["hoist-if-init"] {
+		config.shortenerConfig.HoistIfInit = *c.HoistIfInit
+	}
+	if c.GofumptExtra != nil && !flagsSetByUser["gofumpt-extra"] {
+		config.shortenerConfig.GofumptExtraRules = *c.GofumptExtra
+	}
+	if c.GofumptLangVersion != nil && !flagsSetByUser["gofumpt-lang-version"] {
+		config.shortenerConfig.GofumptLangVersion = *c.GofumptLangVersion
+	}
+	if c.GofumptModulePath != nil && !flagsSetByUser["gofumpt-module-path"] {
+		config.shortenerConfig.GofumptModulePath = *c.GofumptModulePath
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	yamlPath := filepath.Join(tmpDir, ".golines.yaml")
+	writeTestFile(
+		t,
+		yamlPath,
+		"max-len: 120\nchain-split-dots: false\nignored-dirs: [vendor, testdata]\n"+
+			"base-formatter-timeout: 30s\n",
+	)
+
+	config, err := loadConfigFile(yamlPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 120, *config.MaxLen)
+	assert.Equal(t, 30*time.Second, *config.BaseFormatterTimeout)
+	assert.False(t, *config.ChainSplitDots)
+	assert.Equal(t, []string{"vendor", "testdata"}, config.IgnoredDirs)
+	assert.Nil(t, config.TabLen)
+
+	tomlPath := filepath.Join(tmpDir, ".golines.toml")
+	writeTestFile(
+		t,
+		tomlPath,
+		"tab-len = 8\nbase-formatter = \"gofmt\"\nbase-formatter-timeout = \"1m\"\n",
+	)
+
+	config, err = loadConfigFile(tomlPath)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, *config.TabLen)
+	assert.Equal(t, time.Minute, *config.BaseFormatterTimeout)
+	assert.Equal(t, "gofmt", *config.BaseFormatter)
+	assert.Nil(t, config.MaxLen)
+
+	// Unknown keys are rejected
+	writeTestFile(t, yamlPath, "max-length: 120\n")
+	_, err = loadConfigFile(yamlPath)
+	assert.NotNil(t, err)
+
+	writeTestFile(t, tomlPath, "max-length = 120\n")
+	_, err = loadConfigFile(tomlPath)
+	assert.NotNil(t, err)
+
+	// Empty files are fine
+	writeTestFile(t, yamlPath, "")
+	config, err = loadConfigFile(yamlPath)
+	assert.Nil(t, err)
+	assert.Nil(t, config.MaxLen)
+}
+
+func TestResolveConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "config")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	subDir := filepath.Join(tmpDir, "sub", "dir")
+	err = os.MkdirAll(subDir, 0755)
+	if err != nil {
+		t.Fatal("Unexpected error creating sub dir", err)
+	}
+
+	filePath := filepath.Join(tmpDir, "test.go")
+	writeTestFile(t, filePath, "package main\n")
+	subFilePath := filepath.Join(subDir, "test.go")
+	writeTestFile(t, subFilePath, "package main\n")
+
+	configPath := filepath.Join(tmpDir, ".golines.yaml")
+	writeTestFile(t, configPath, "max-len: 120\ntab-len: 8\nreformat-tags: false\n")
+
+	foundPath, err := findConfigFile(tmpDir)
+	assert.Nil(t, err)
+	assert.Equal(t, configPath, foundPath)
+
+	origMaxLen, origTabLen, origReformatTags := maxLen, tabLen, reformatTags
+	origFlagsSetByUser := flagsSetByUser
+	defer func() {
+		maxLen, tabLen, reformatTags = origMaxLen, origTabLen, origReformatTags
+		flagsSetByUser = origFlagsSetByUser
+	}()
+
+	maxLen = intPtr(100)
+	tabLen = intPtr(4)
+	reformatTags = boolPtr(true)
+	flagsSetByUser = map[string]bool{"tab-len": true}
+
+	// Config file values are used unless the flag was set explicitly
+	resolver := newConfigResolver()
+	config, err := resolver.resolve(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 120, config.shortenerConfig.MaxLen)
+	assert.Equal(t, 4, config.shortenerConfig.TabLen)
+	assert.False(t, config.shortenerConfig.ReformatTags)
+
+	// Nested config files override a subset of the settings in their parents
+	writeTestFile(t, filepath.Join(tmpDir, "sub", ".golines.toml"), "max-len = 140\n")
+
+	resolver = newConfigResolver()
+	config, err = resolver.resolve(subFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 140, config.shortenerConfig.MaxLen)
+	assert.Equal(t, 4, config.shortenerConfig.TabLen)
+	assert.False(t, config.shortenerConfig.ReformatTags)
+
+	config, err = resolver.resolve(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 120, config.shortenerConfig.MaxLen)
+
+	// Config files with root set don't inherit from their parents
+	writeTestFile(
+		t,
+		filepath.Join(subDir, ".golines.yaml"),
+		"root: true\nchain-split-dots: false\n",
+	)
+
+	resolver = newConfigResolver()
+	config, err = resolver.resolve(subFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, 100, config.shortenerConfig.MaxLen)
+	assert.True(t, config.shortenerConfig.ReformatTags)
+	assert.False(t, config.shortenerConfig.ChainSplitDots)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func writeTestFile(t *testing.T, path string, contents string) {
+	err := os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal("Unexpected error writing file", err)
+	}
+}
@@ -15,7 +15,7 @@ const (
 // graph node. The latter can then be used to generate a dot file for debugging
 // purposes.
 func genNodeToGraphNode() error {
-	f := jen.NewFile("main")
+	f := jen.NewFile("shorten")
 	f.HeaderComment("Generated by generate/main.go. DO NOT EDIT.")
 
 	// Sort the keys in the dst map so that output is in stable order
@@ -103,7 +103,7 @@ func genNodeToGraphNode() error {
 		},
 	)
 
-	f.Save("../graph_generated.go")
+	f.Save("../shorten/graph_generated.go")
 
 	return nil
 }
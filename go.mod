@@ -3,6 +3,7 @@ module github.com/segmentio/golines
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/alecthomas/kingpin/v2 v2.4.0
 	github.com/dave/dst v0.27.3
 	github.com/dave/jennifer v1.7.1
@@ -12,6 +13,9 @@ require (
 	github.com/stretchr/testify v1.10.0
 	github.com/x-cray/logrus-prefixed-formatter v0.5.2
 	golang.org/x/term v0.34.0
+	golang.org/x/tools v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
+	mvdan.cc/gofumpt v0.8.0
 )
 
 require (
@@ -28,6 +32,4 @@ require (
 	golang.org/x/mod v0.27.0 // indirect
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/sys v0.35.0 // indirect
-	golang.org/x/tools v0.36.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/segmentio/golines/shorten"
+	log "github.com/sirupsen/logrus"
+)
+
+// Header at the start of each hunk in a unified diff, e.g. "@@ -10,2 +10,3 @@"
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// lineSelection determines which lines should be shortened in each file when only some of
+// them should be, e.g. the ones that changed relative to a git revision.
+type lineSelection struct {
+	// Ranges from the --lines or --range flags; these apply to all inputs
+	ranges []shorten.LineRange
+
+	// Range from the --range flag when it's given as byte offsets; this is converted to a
+	// range of lines separately for each input
+	byteRange *shorten.ByteRange
+
+	// Ranges from a diff, keyed by absolute file path with symlinks resolved
+	fileRanges map[string][]shorten.LineRange
+}
+
+// newLineSelection creates a lineSelection from the --lines, --range, --diff-base, and
+// --diff-stdin flags. It returns nil if none of these are set, in which case all lines in
+// all files should be shortened.
+func newLineSelection() (*lineSelection, error) {
+	numSet := 0
+	for _, set := range []bool{
+		len(*lineRanges) > 0,
+		*selectionRange != "",
+		*diffBase != "",
+		*diffStdin,
+	} {
+		if set {
+			numSet++
+		}
+	}
+
+	switch {
+	case numSet == 0:
+		return nil, nil
+	case numSet > 1:
+		return nil, errors.New(
+			"only one of --lines, --range, --diff-base, and --diff-stdin can be set",
+		)
+	case len(*lineRanges) > 0:
+		selection := &lineSelection{}
+
+		for _, value := range *lineRanges {
+			lineRange, err := shorten.ParseLineRange(value)
+			if err != nil {
+				return nil, err
+			}
+			selection.ranges = append(selection.ranges, lineRange)
+		}
+
+		return selection, nil
+	case strings.HasPrefix(*selectionRange, "#"):
+		byteRange, err := shorten.ParseByteRange(*selectionRange)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lineSelection{byteRange: &byteRange}, nil
+	case *selectionRange != "":
+		lineRange, err := shorten.ParseLineRange(*selectionRange)
+		if err != nil {
+			return nil, err
+		}
+
+		return &lineSelection{ranges: []shorten.LineRange{lineRange}}, nil
+	}
+
+	if len(*paths) == 0 {
+		return nil, errors.New("--diff-base and --diff-stdin require paths to format")
+	}
+
+	var diff []byte
+	var root string
+	var err error
+
+	if *diffBase != "" {
+		diff, root, err = gitDiff(*diffBase)
+	} else {
+		diff, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fileRanges, err := parseUnifiedDiff(diff, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lineSelection{fileRanges: fileRanges}, nil
+}
+
+// forFile returns the line ranges that should be shortened in the file with the provided
+// path and contents. The second return value is false if all lines should be shortened.
+func (l *lineSelection) forFile(path string, contents []byte) ([]shorten.LineRange, bool) {
+	if l == nil {
+		return nil, false
+	} else if l.byteRange != nil {
+		return []shorten.LineRange{l.byteRange.LineRange(contents)}, true
+	} else if l.fileRanges == nil {
+		return l.ranges, true
+	}
+
+	resolvedPath, err := resolvePath(path)
+	if err != nil {
+		return nil, true
+	}
+	return l.fileRanges[resolvedPath], true
+}
+
+// resolvePath returns the absolute path of the provided file with any symlinks resolved so
+// that paths from the diff and the command line can be compared. Since files in a diff might
+// not exist anymore, the path is only made absolute if it can't be resolved.
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		return absPath, nil
+	}
+	return resolvedPath, nil
+}
+
+// gitDiff runs git diff against the provided revision. It returns the diff output along
+// with the root of the git repo, which the paths in the diff are relative to.
+func gitDiff(rev string) ([]byte, string, error) {
+	root, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The prefixes and paths are set explicitly since they can be changed via git config, e.g.
+	// diff.mnemonicPrefix and diff.relative
+	diff, err := runGit(
+		"diff",
+		"-U0",
+		"--no-color",
+		"--no-ext-diff",
+		"--no-relative",
+		"--src-prefix=a/",
+		"--dst-prefix=b/",
+		rev,
+		"--",
+	)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return diff, strings.TrimSpace(string(root)), nil
+}
+
+// runGit runs git with the provided arguments and returns its stdout.
+func runGit(args ...string) ([]byte, error) {
+	log.Debugf("running git %s", strings.Join(args, " "))
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(
+			"error running git %s: %+v: %s",
+			strings.Join(args, " "),
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return out, nil
+}
+
+// parseUnifiedDiff extracts the ranges of added or changed lines in each file from a
+// unified diff; context lines are not included. The results are keyed by absolute path (with
+// symlinks resolved), with relative paths in the diff interpreted relative to root (or the
+// current directory if root is empty).
+func parseUnifiedDiff(diff []byte, root string) (map[string][]shorten.LineRange, error) {
+	fileRanges := map[string][]shorten.LineRange{}
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(nil, 1024*1024)
+
+	var oldPath, currPath string
+
+	// Number of lines remaining in the current hunk on the old and new sides; these are
+	// tracked so that removed or added lines starting with "--" or "++" aren't mistaken for
+	// file headers
+	var oldRemaining, newRemaining int
+
+	// Line number of the next line on the new side of the current hunk
+	var newLine int
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if oldRemaining > 0 || newRemaining > 0 {
+			switch {
+			case strings.HasPrefix(line, "-"):
+				oldRemaining--
+			case strings.HasPrefix(line, "+"):
+				if currPath != "" {
+					fileRanges[currPath] = addLine(fileRanges[currPath], newLine)
+				}
+				newRemaining--
+				newLine++
+			case strings.HasPrefix(line, " "), line == "":
+				oldRemaining--
+				newRemaining--
+				newLine++
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "--- "):
+			oldPath = diffPath(line[4:])
+		case strings.HasPrefix(line, "+++ "):
+			currPath = diffPath(line[4:])
+			if currPath == "/dev/null" {
+				// File was deleted
+				currPath = ""
+				continue
+			}
+
+			// Strip the a/ and b/ prefixes that git adds by default
+			if strings.HasPrefix(oldPath, "a/") && strings.HasPrefix(currPath, "b/") ||
+				oldPath == "/dev/null" && strings.HasPrefix(currPath, "b/") {
+				currPath = currPath[2:]
+			}
+
+			if !filepath.IsAbs(currPath) {
+				currPath = filepath.Join(root, currPath)
+			}
+
+			resolvedPath, err := resolvePath(currPath)
+			if err != nil {
+				return nil, err
+			}
+			currPath = resolvedPath
+		default:
+			matches := hunkHeader.FindStringSubmatch(line)
+			if matches == nil {
+				continue
+			}
+
+			oldCount, err := hunkCount(matches[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header %q: %+v", line, err)
+			}
+			start, err := strconv.Atoi(matches[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header %q: %+v", line, err)
+			}
+			count, err := hunkCount(matches[3])
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header %q: %+v", line, err)
+			}
+
+			oldRemaining = oldCount
+			newRemaining = count
+			newLine = start
+		}
+	}
+
+	return fileRanges, scanner.Err()
+}
+
+// addLine adds the provided line number to a list of ranges, extending the last range if
+// the line immediately follows it.
+func addLine(ranges []shorten.LineRange, line int) []shorten.LineRange {
+	if len(ranges) > 0 && ranges[len(ranges)-1].End == line-1 {
+		ranges[len(ranges)-1].End = line
+		return ranges
+	}
+	return append(ranges, shorten.LineRange{Start: line, End: line})
+}
+
+// hunkCount parses the number of lines in one side of a hunk header, which defaults to 1
+// if omitted.
+func hunkCount(value string) (int, error) {
+	if value == "" {
+		return 1, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// diffPath extracts the path from the value of a ---/+++ line in a unified diff, removing
+// the timestamp that some tools add after a tab.
+func diffPath(value string) string {
+	if index := strings.Index(value, "\t"); index >= 0 {
+		value = value[:index]
+	}
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/golines/shorten"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDiff = `diff --git a/pkg/file1.go b/pkg/file1.go
+index 1234567..89abcde 100644
+--- a/pkg/file1.go
++++ b/pkg/file1.go
+@@ -10 +10 @@ func main() {
+-	x := 1
++	x := 2
+@@ -20,0 +21,3 @@ func main() {
++	y := 3
++	z := 4
+++++ not a header
+@@ -30,2 +33,0 @@ func main() {
+-	removed := 1
+--- not a header either
+diff --git a/file2.go b/file2.go
+new file mode 100644
+--- /dev/null
++++ b/file2.go
+@@ -0,0 +1,2 @@
++package main
++
+diff --git a/file3.go b/file3.go
+deleted file mode 100644
+--- a/file3.go
++++ /dev/null
+@@ -1,1 +0,0 @@
+-package main
+diff --git a/file4.go b/file4.go
+--- a/file4.go
++++ b/file4.go
+@@ -1,3 +1,4 @@
+ package main
+
++// Added with context
+ func main() {}
+`
+
+func TestParseUnifiedDiff(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "repo")
+
+	fileRanges, err := parseUnifiedDiff([]byte(testDiff), root)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		map[string][]shorten.LineRange{
+			filepath.Join(root, "pkg", "file1.go"): {
+				{Start: 10, End: 10},
+				{Start: 21, End: 23},
+			},
+			filepath.Join(root, "file2.go"): {
+				{Start: 1, End: 2},
+			},
+			filepath.Join(root, "file4.go"): {
+				{Start: 3, End: 3},
+			},
+		},
+		fileRanges,
+	)
+}
+
+func TestLineSelection(t *testing.T) {
+	var selection *lineSelection
+	_, restricted := selection.forFile("file.go", nil)
+	assert.False(t, restricted)
+
+	selection = &lineSelection{ranges: []shorten.LineRange{{Start: 1, End: 2}}}
+	ranges, restricted := selection.forFile("file.go", nil)
+	assert.True(t, restricted)
+	assert.Equal(t, []shorten.LineRange{{Start: 1, End: 2}}, ranges)
+
+	absPath, err := filepath.Abs("file.go")
+	assert.Nil(t, err)
+
+	selection = &lineSelection{
+		fileRanges: map[string][]shorten.LineRange{
+			absPath: {{Start: 3, End: 4}},
+		},
+	}
+	ranges, restricted = selection.forFile("file.go", nil)
+	assert.True(t, restricted)
+	assert.Equal(t, []shorten.LineRange{{Start: 3, End: 4}}, ranges)
+
+	ranges, restricted = selection.forFile("other.go", nil)
+	assert.True(t, restricted)
+	assert.Nil(t, ranges)
+
+	selection = &lineSelection{byteRange: &shorten.ByteRange{Start: 13, End: 20}}
+	ranges, restricted = selection.forFile("file.go", []byte("package main\n\nfunc main() {}\n"))
+	assert.True(t, restricted)
+	assert.Equal(t, []shorten.LineRange{{Start: 2, End: 3}}, ranges)
+}
+
+func TestLineSelectionSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	link := filepath.Join(dir, "link")
+
+	assert.Nil(t, os.Mkdir(repo, 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(repo, "file2.go"), []byte("package main\n"), 0644))
+	assert.Nil(t, os.Symlink(repo, link))
+
+	// git resolves symlinks in the repo root, but the paths of the files might not be
+	fileRanges, err := parseUnifiedDiff([]byte(testDiff), repo)
+	assert.Nil(t, err)
+
+	selection := &lineSelection{fileRanges: fileRanges}
+	ranges, restricted := selection.forFile(filepath.Join(link, "file2.go"), nil)
+	assert.True(t, restricted)
+	assert.Equal(t, []shorten.LineRange{{Start: 1, End: 2}}, ranges)
+}
+
+func TestGitDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	assert.Nil(t, err)
+	subDir := filepath.Join(repo, "sub")
+	assert.Nil(t, os.Mkdir(subDir, 0755))
+
+	runTestGit := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Unexpected error running git %v: %+v: %s", args, err, out)
+		}
+	}
+
+	filePath := filepath.Join(subDir, "file.go")
+	writeTestFile(t, filePath, "package sub\n")
+
+	runTestGit("init", "-q")
+	runTestGit("add", ".")
+	runTestGit("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-qm", "init")
+
+	// Settings that change the format of the paths in the diff are ignored
+	runTestGit("config", "diff.mnemonicPrefix", "true")
+	runTestGit("config", "diff.relative", "true")
+
+	writeTestFile(t, filePath, "package sub\n\nvar x = 1\n")
+
+	wd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(subDir))
+	defer func() {
+		assert.Nil(t, os.Chdir(wd))
+	}()
+
+	diff, root, err := gitDiff("HEAD")
+	assert.Nil(t, err)
+
+	fileRanges, err := parseUnifiedDiff(diff, root)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		map[string][]shorten.LineRange{filePath: {{Start: 2, End: 3}}},
+		fileRanges,
+	)
+}
@@ -0,0 +1,15 @@
+/*
+Package lsp implements a minimal language server that exposes the golines shortener to
+editors via the textDocument/formatting and textDocument/rangeFormatting requests.
+
+The server's options can be set via the "golines" section of the client's workspace
+configuration, e.g.:
+
+	{
+		"golines": {
+			"maxLen": 120,
+			"shortenComments": true
+		}
+	}
+*/
+package lsp // import "github.com/segmentio/golines/lsp"
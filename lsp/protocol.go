@@ -0,0 +1,190 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/segmentio/golines/shorten"
+)
+
+// JSON-RPC and LSP error codes
+const (
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeRequestFailed  = -32803
+)
+
+// message is a JSON-RPC 2.0 message. Requests have both an ID and a method, notifications
+// only have a method, and responses only have an ID.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+// responseError is the error in a JSON-RPC response.
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// readMessage reads a single message, including its base protocol headers, from the
+// provided reader.
+func readMessage(reader *bufio.Reader) (*message, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF && len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("error reading message headers: %+v", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(headers.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", headers.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("error reading message body: %+v", err)
+	}
+
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return nil, fmt.Errorf("error parsing message: %+v", err)
+	}
+	return msg, nil
+}
+
+// writeMessage writes a single message, including its base protocol headers, to the
+// provided writer.
+func writeMessage(writer io.Writer, msg *message) error {
+	msg.JSONRPC = "2.0"
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// The subset of the LSP types that are used by the server. See
+// https://microsoft.github.io/language-server-protocol/specifications/lsp/3.17/specification/
+// for the full definitions.
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"` // Offset in UTF-16 code units
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type initializeParams struct {
+	Capabilities struct {
+		Workspace struct {
+			Configuration bool `json:"configuration"`
+		} `json:"workspace"`
+	} `json:"capabilities"`
+}
+
+type didOpenParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeParams struct {
+	TextDocument   textDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type didCloseParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type didChangeConfigurationParams struct {
+	Settings struct {
+		Golines *settings `json:"golines"`
+	} `json:"settings"`
+}
+
+type documentFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type documentRangeFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Range        textRange              `json:"range"`
+}
+
+type configurationItem struct {
+	Section string `json:"section"`
+}
+
+type configurationParams struct {
+	Items []configurationItem `json:"items"`
+}
+
+// offsetPosition returns the position of the provided byte offset in the contents.
+func offsetPosition(contents []byte, offset int) position {
+	lines := strings.Split(string(contents[:offset]), "\n")
+	lastLine := lines[len(lines)-1]
+
+	return position{
+		Line:      len(lines) - 1,
+		Character: len(utf16.Encode([]rune(lastLine))),
+	}
+}
+
+// convertEdits converts edits from the shortener, which use byte offsets, into LSP edits.
+func convertEdits(contents []byte, edits []shorten.TextEdit) []textEdit {
+	converted := []textEdit{}
+
+	for _, edit := range edits {
+		converted = append(
+			converted,
+			textEdit{
+				Range: textRange{
+					Start: offsetPosition(contents, edit.Offset),
+					End:   offsetPosition(contents, edit.Offset+edit.Length),
+				},
+				NewText: edit.NewText,
+			},
+		)
+	}
+
+	return converted
+}
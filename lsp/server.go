@@ -0,0 +1,425 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/segmentio/golines/shorten"
+	log "github.com/sirupsen/logrus"
+)
+
+// Section of the client's workspace configuration that the settings are read from
+const configurationSection = "golines"
+
+// settings are the shortener options that can be set via the client's workspace
+// configuration. Options that aren't set keep the server's defaults.
+type settings struct {
+	MaxLen          *int    `json:"maxLen"`
+	TabLen          *int    `json:"tabLen"`
+	ShortenComments *bool   `json:"shortenComments"`
+	ReformatTags    *bool   `json:"reformatTags"`
+	IgnoreGenerated *bool   `json:"ignoreGenerated"`
+	BaseFormatter   *string `json:"baseFormatter"`
+	LocalPrefixes   *string `json:"localPrefixes"`
+	ChainSplitDots  *bool   `json:"chainSplitDots"`
+	SplitStrings    *bool   `json:"splitStrings"`
+	HoistIfInit     *bool   `json:"hoistIfInit"`
+
+	GofumptExtra       *bool   `json:"gofumptExtra"`
+	GofumptLangVersion *string `json:"gofumptLangVersion"`
+	GofumptModulePath  *string `json:"gofumptModulePath"`
+}
+
+// apply overrides the values in the provided config with the ones that are set.
+func (s settings) apply(config *shorten.ShortenerConfig) {
+	if s.MaxLen != nil {
+		config.MaxLen = *s.MaxLen
+	}
+	if s.TabLen != nil {
+		config.TabLen = *s.TabLen
+	}
+	if s.ShortenComments != nil {
+		config.ShortenComments = *s.ShortenComments
+	}
+	if s.ReformatTags != nil {
+		config.ReformatTags = *s.ReformatTags
+	}
+	if s.IgnoreGenerated != nil {
+		config.IgnoreGenerated = *s.IgnoreGenerated
+	}
+	if s.BaseFormatter != nil {
+		config.BaseFormatterCmd = *s.BaseFormatter
+	}
+	if s.LocalPrefixes != nil {
+		config.LocalPrefixes = *s.LocalPrefixes
+	}
+	if s.ChainSplitDots != nil {
+		config.ChainSplitDots = *s.ChainSplitDots
+	}
+	if s.SplitStrings != nil {
+		config.SplitStrings = *s.SplitStrings
+	}
+	if s.HoistIfInit != nil {
+		config.HoistIfInit = *s.HoistIfInit
+	}
+	if s.GofumptExtra != nil {
+		config.GofumptExtraRules = *s.GofumptExtra
+	}
+	if s.GofumptLangVersion != nil {
+		config.GofumptLangVersion = *s.GofumptLangVersion
+	}
+	if s.GofumptModulePath != nil {
+		config.GofumptModulePath = *s.GofumptModulePath
+	}
+}
+
+// Server is a language server that provides document formatting via a Shortener. It
+// communicates with a single client using JSON-RPC over a pair of streams, typically the
+// process's stdin and stdout.
+//
+// Requests are handled one at a time, in the order that they're received.
+type Server struct {
+	reader *bufio.Reader
+	writer io.Writer
+
+	defaultConfig shorten.ShortenerConfig
+	config        shorten.ShortenerConfig
+	shortener     *shorten.Shortener
+
+	// Contents of the documents that are open in the client, keyed by URI
+	documents map[string][]byte
+
+	// Whether the client supports workspace/configuration requests
+	supportsConfiguration bool
+
+	// Methods of the requests that were sent to the client and are awaiting responses,
+	// keyed by ID
+	pending map[string]string
+	nextID  int
+
+	shutdown bool
+}
+
+// NewServer creates a new Server that reads messages from in and writes them to out. The
+// provided config is used for any options that aren't set in the client's workspace
+// configuration.
+func NewServer(in io.Reader, out io.Writer, config shorten.ShortenerConfig) *Server {
+	return &Server{
+		reader:        bufio.NewReader(in),
+		writer:        out,
+		defaultConfig: config,
+		config:        config,
+		shortener:     shorten.NewShortener(config),
+		documents:     map[string][]byte{},
+		pending:       map[string]string{},
+		nextID:        1,
+	}
+}
+
+// Run processes messages until the client sends an exit notification or closes the input
+// stream.
+func (s *Server) Run() error {
+	for {
+		msg, err := readMessage(s.reader)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch {
+		case msg.Method == "" && msg.ID != nil:
+			s.handleResponse(msg)
+		case msg.ID != nil:
+			err = s.respond(msg)
+		case msg.Method == "exit":
+			return nil
+		default:
+			err = s.handleNotification(msg)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// respond handles a request from the client and sends the response.
+func (s *Server) respond(msg *message) error {
+	response := &message{ID: msg.ID}
+
+	result, err := s.handleRequest(msg.Method, msg.Params)
+	if err != nil {
+		respErr, ok := err.(*responseError)
+		if !ok {
+			respErr = &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		log.Debugf("error handling %s request: %+v", msg.Method, respErr)
+		response.Error = respErr
+	} else {
+		response.Result, err = json.Marshal(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeMessage(s.writer, response)
+}
+
+// handleRequest handles a request from the client and returns the result.
+func (s *Server) handleRequest(method string, params json.RawMessage) (interface{}, error) {
+	if s.shutdown {
+		return nil, &responseError{
+			Code:    codeInvalidRequest,
+			Message: "server is shutting down",
+		}
+	}
+
+	switch method {
+	case "initialize":
+		initParams := initializeParams{}
+		if err := unmarshalParams(params, &initParams); err != nil {
+			return nil, err
+		}
+		s.supportsConfiguration = initParams.Capabilities.Workspace.Configuration
+
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// Full document sync
+				"textDocumentSync":                1,
+				"documentFormattingProvider":      true,
+				"documentRangeFormattingProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "golines"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/formatting":
+		formattingParams := documentFormattingParams{}
+		if err := unmarshalParams(params, &formattingParams); err != nil {
+			return nil, err
+		}
+
+		return s.format(formattingParams.TextDocument.URI)
+	case "textDocument/rangeFormatting":
+		formattingParams := documentRangeFormattingParams{}
+		if err := unmarshalParams(params, &formattingParams); err != nil {
+			return nil, err
+		}
+
+		return s.formatRange(
+			formattingParams.TextDocument.URI,
+			lineRange(formattingParams.Range),
+		)
+	default:
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: fmt.Sprintf("method not supported: %s", method),
+		}
+	}
+}
+
+// handleNotification handles a notification from the client.
+func (s *Server) handleNotification(msg *message) error {
+	switch msg.Method {
+	case "initialized":
+		return s.requestConfiguration()
+	case "workspace/didChangeConfiguration":
+		if s.supportsConfiguration {
+			return s.requestConfiguration()
+		}
+
+		// Fall back to the settings included in the notification, if any
+		changeParams := didChangeConfigurationParams{}
+		if err := unmarshalParams(msg.Params, &changeParams); err != nil {
+			log.Debugf("ignoring invalid configuration: %+v", err)
+		} else if changeParams.Settings.Golines != nil {
+			s.updateSettings(*changeParams.Settings.Golines)
+		}
+	case "textDocument/didOpen":
+		openParams := didOpenParams{}
+		if err := unmarshalParams(msg.Params, &openParams); err == nil {
+			s.documents[openParams.TextDocument.URI] = []byte(openParams.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		changeParams := didChangeParams{}
+		if err := unmarshalParams(msg.Params, &changeParams); err == nil &&
+			len(changeParams.ContentChanges) > 0 {
+			// With full document sync, the last change has the complete contents
+			s.documents[changeParams.TextDocument.URI] = []byte(
+				changeParams.ContentChanges[len(changeParams.ContentChanges)-1].Text,
+			)
+		}
+	case "textDocument/didClose":
+		closeParams := didCloseParams{}
+		if err := unmarshalParams(msg.Params, &closeParams); err == nil {
+			delete(s.documents, closeParams.TextDocument.URI)
+		}
+	default:
+		log.Debugf("ignoring notification: %s", msg.Method)
+	}
+
+	return nil
+}
+
+// handleResponse handles a response to one of the requests that the server sent to the
+// client.
+func (s *Server) handleResponse(msg *message) {
+	id := string(msg.ID)
+	method, ok := s.pending[id]
+	if !ok {
+		log.Debugf("ignoring response to unknown request %s", id)
+		return
+	}
+	delete(s.pending, id)
+
+	if msg.Error != nil {
+		log.Debugf("error response to %s request: %+v", method, msg.Error)
+		return
+	}
+
+	if method == "workspace/configuration" {
+		results := []*settings{}
+		if err := json.Unmarshal(msg.Result, &results); err != nil {
+			log.Debugf("ignoring invalid configuration: %+v", err)
+		} else if len(results) > 0 && results[0] != nil {
+			s.updateSettings(*results[0])
+		}
+	}
+}
+
+// requestConfiguration asks the client for the golines section of its workspace
+// configuration, if supported. The settings are updated once the response arrives.
+func (s *Server) requestConfiguration() error {
+	if !s.supportsConfiguration {
+		return nil
+	}
+
+	params, err := json.Marshal(
+		configurationParams{
+			Items: []configurationItem{{Section: configurationSection}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	id := strconv.Itoa(s.nextID)
+	s.nextID++
+	s.pending[id] = "workspace/configuration"
+
+	return writeMessage(
+		s.writer,
+		&message{
+			ID:     json.RawMessage(id),
+			Method: "workspace/configuration",
+			Params: params,
+		},
+	)
+}
+
+// updateSettings replaces the current settings, which are applied on top of the defaults.
+func (s *Server) updateSettings(newSettings settings) {
+	config := s.defaultConfig
+	newSettings.apply(&config)
+
+	log.Debugf("updating config: %+v", config)
+	s.config = config
+	s.shortener = shorten.NewShortener(config)
+}
+
+// format shortens the document with the provided URI and returns the edits needed to
+// update it.
+func (s *Server) format(uri string) ([]textEdit, error) {
+	contents, err := s.document(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.IgnoreGenerated && shorten.IsGenerated(contents) {
+		return []textEdit{}, nil
+	}
+
+	edits, err := s.shortener.WithFilename(uriPath(uri)).ShortenEdits(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertEdits(contents, edits), nil
+}
+
+// formatRange shortens the lines in the provided range of the document with the provided
+// URI and returns the edits needed to update it.
+func (s *Server) formatRange(uri string, lineRange shorten.LineRange) ([]textEdit, error) {
+	contents, err := s.document(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.IgnoreGenerated && shorten.IsGenerated(contents) {
+		return []textEdit{}, nil
+	}
+
+	edits, err := s.shortener.WithFilename(uriPath(uri)).ShortenRange(contents, lineRange)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertEdits(contents, edits), nil
+}
+
+// document returns the contents of the document with the provided URI, reading it from
+// disk if it isn't open in the client.
+func (s *Server) document(uri string) ([]byte, error) {
+	if contents, ok := s.documents[uri]; ok {
+		return contents, nil
+	}
+
+	path := uriPath(uri)
+	if path == "" {
+		return nil, fmt.Errorf("unsupported document URI: %s", uri)
+	}
+	return os.ReadFile(path)
+}
+
+// uriPath returns the path of the file with the provided URI, or an empty string if it isn't
+// a file URI.
+func uriPath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return ""
+	}
+	return parsed.Path
+}
+
+// lineRange converts an LSP range to the range of (1-based) lines that it covers. A range
+// that ends at the start of a line doesn't include that line.
+func lineRange(r textRange) shorten.LineRange {
+	end := r.End.Line + 1
+	if r.End.Character == 0 && r.End.Line > r.Start.Line {
+		end = r.End.Line
+	}
+
+	return shorten.LineRange{Start: r.Start.Line + 1, End: end}
+}
+
+// unmarshalParams parses the params of a request or notification.
+func unmarshalParams(params json.RawMessage, value interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(params, value); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: fmt.Sprintf("invalid params: %+v", err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/segmentio/golines/shorten"
+	"github.com/stretchr/testify/assert"
+)
+
+const testURI = "file:///tmp/test.go"
+
+const testContents = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`
+
+func TestServer(t *testing.T) {
+	in := &bytes.Buffer{}
+	writeTestMessage(
+		t,
+		in,
+		`1`,
+		"initialize",
+		`{"capabilities":{"workspace":{"configuration":true}}}`,
+	)
+	writeTestMessage(t, in, "", "initialized", `{}`)
+
+	// Response to the server's workspace/configuration request
+	err := writeMessage(
+		in,
+		&message{ID: json.RawMessage(`1`), Result: json.RawMessage(`[{"maxLen":100}]`)},
+	)
+	assert.Nil(t, err)
+
+	writeTestMessage(
+		t,
+		in,
+		"",
+		"textDocument/didOpen",
+		`{"textDocument":{"uri":"`+testURI+`","languageId":"go","version":1,"text":`+
+			jsonString(t, testContents)+`}}`,
+	)
+	writeTestMessage(
+		t,
+		in,
+		`2`,
+		"textDocument/formatting",
+		`{"textDocument":{"uri":"`+testURI+`"}}`,
+	)
+	writeTestMessage(
+		t,
+		in,
+		`3`,
+		"textDocument/rangeFormatting",
+		`{"textDocument":{"uri":"`+testURI+`"},`+
+			`"range":{"start":{"line":6,"character":0},"end":{"line":7,"character":0}}}`,
+	)
+	writeTestMessage(t, in, `4`, "textDocument/unknown", `{}`)
+	writeTestMessage(t, in, `5`, "shutdown", ``)
+	writeTestMessage(t, in, "", "exit", ``)
+
+	out := &bytes.Buffer{}
+	server := NewServer(
+		in,
+		out,
+		shorten.ShortenerConfig{
+			MaxLen:           20,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+	assert.Nil(t, server.Run())
+
+	responses := readTestMessages(t, out)
+	if !assert.Equal(t, 6, len(responses)) {
+		return
+	}
+
+	// initialize
+	assert.Equal(t, `1`, string(responses[0].ID))
+	assert.Contains(t, string(responses[0].Result), `"documentFormattingProvider":true`)
+
+	// workspace/configuration request sent by the server
+	assert.Equal(t, "workspace/configuration", responses[1].Method)
+	assert.JSONEq(t, `{"items":[{"section":"golines"}]}`, string(responses[1].Params))
+	assert.Equal(t, 100, server.config.MaxLen)
+
+	// Formatting only replaces the lines that changed
+	edits := []textEdit{}
+	assert.Nil(t, json.Unmarshal(responses[2].Result, &edits))
+	if assert.Equal(t, 1, len(edits)) {
+		assert.Equal(
+			t,
+			textRange{Start: position{Line: 5}, End: position{Line: 7}},
+			edits[0].Range,
+		)
+		assert.Equal(t, 2, bytes.Count([]byte(edits[0].NewText), []byte(`"argument6",`)))
+	}
+
+	// Range formatting only shortens the second call, and only replaces that line
+	edits = []textEdit{}
+	assert.Nil(t, json.Unmarshal(responses[3].Result, &edits))
+	if assert.Equal(t, 1, len(edits)) {
+		assert.Equal(
+			t,
+			textRange{Start: position{Line: 6}, End: position{Line: 7}},
+			edits[0].Range,
+		)
+		assert.Equal(t, 1, bytes.Count([]byte(edits[0].NewText), []byte(`"argument6",`)))
+	}
+
+	// Unknown methods return an error
+	if assert.NotNil(t, responses[4].Error) {
+		assert.Equal(t, codeMethodNotFound, responses[4].Error.Code)
+	}
+
+	// shutdown
+	assert.Equal(t, `5`, string(responses[5].ID))
+	assert.Equal(t, `null`, string(responses[5].Result))
+}
+
+func TestLineRange(t *testing.T) {
+	assert.Equal(
+		t,
+		shorten.LineRange{Start: 3, End: 5},
+		lineRange(textRange{Start: position{Line: 2}, End: position{Line: 4, Character: 3}}),
+	)
+	assert.Equal(
+		t,
+		shorten.LineRange{Start: 3, End: 4},
+		lineRange(textRange{Start: position{Line: 2}, End: position{Line: 4}}),
+	)
+	assert.Equal(
+		t,
+		shorten.LineRange{Start: 3, End: 3},
+		lineRange(textRange{Start: position{Line: 2}, End: position{Line: 2}}),
+	)
+}
+
+func TestOffsetPosition(t *testing.T) {
+	assert.Equal(t, position{Line: 0, Character: 0}, offsetPosition([]byte(""), 0))
+	assert.Equal(t, position{Line: 0, Character: 4}, offsetPosition([]byte("package main\n"), 4))
+	assert.Equal(t, position{Line: 1, Character: 0}, offsetPosition([]byte("package main\n"), 13))
+
+	// Characters are counted in UTF-16 code units
+	assert.Equal(t, position{Line: 1, Character: 5}, offsetPosition([]byte("\n// 😀"), 8))
+}
+
+func writeTestMessage(t *testing.T, w io.Writer, id string, method string, params string) {
+	msg := &message{Method: method}
+	if id != "" {
+		msg.ID = json.RawMessage(id)
+	}
+	if params != "" {
+		msg.Params = json.RawMessage(params)
+	}
+
+	if err := writeMessage(w, msg); err != nil {
+		t.Fatal("Unexpected error writing message", err)
+	}
+}
+
+func readTestMessages(t *testing.T, r io.Reader) []*message {
+	messages := []*message{}
+	reader := bufio.NewReader(r)
+
+	for {
+		msg, err := readMessage(reader)
+		if err == io.EOF {
+			return messages
+		} else if err != nil {
+			t.Fatal("Unexpected error reading message", err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func jsonString(t *testing.T, value string) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal("Unexpected error encoding string", err)
+	}
+	return string(encoded)
+}
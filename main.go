@@ -7,14 +7,29 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
+	"github.com/segmentio/golines/lsp"
+	"github.com/segmentio/golines/shorten"
 	log "github.com/sirupsen/logrus"
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 )
 
+const (
+	// Exit code used when golines fails, e.g. because a file can't be parsed
+	exitCodeError = 1
+
+	// Exit code used in check mode when one or more files would be reformatted
+	exitCodeCheckFailed = 2
+)
+
+// errCheckFailed is returned by run in check mode when one or more files would be reformatted.
+var errCheckFailed = errors.New("check failed")
+
 var (
 	// these values are provided automatically by Goreleaser
 	//   ref: https://goreleaser.com/customization/builds/
@@ -25,44 +40,111 @@ var (
 	// Flags
 	baseFormatterCmd = kingpin.Flag(
 		"base-formatter",
-		"Base formatter to use").Default("").String()
+		"Base formatter command to use; {{path}} is replaced with the path of each file. "+
+			"goimports, gofmt, and gofumpt are run in-process").Default("").String()
+	baseFormatterTimeout = kingpin.Flag(
+		"base-formatter-timeout",
+		"Maximum time the base formatter command can run for on each file; 0 means no limit").
+		Default("0s").Duration()
 	chainSplitDots = kingpin.Flag(
 		"chain-split-dots",
 		"Split chained methods on the dots as opposed to the arguments").
 		Default("true").Bool()
+	check = kingpin.Flag(
+		"check",
+		"Check whether files would be reformatted; prints the names of any that would "+
+			"(plus diffs if --dry-run is set) and exits with status 2").Default("false").Bool()
+	configFile = kingpin.Flag(
+		"config",
+		"Path to config file; if unset, a .golines.yaml or .golines.toml file is searched "+
+			"for in the parent directories of each path").Default("").String()
 	debug = kingpin.Flag(
 		"debug",
 		"Show debug output").Short('d').Default("false").Bool()
+	diffBase = kingpin.Flag(
+		"diff-base",
+		"Only shorten lines that changed relative to this git revision").Default("").String()
+	diffStdin = kingpin.Flag(
+		"diff-stdin",
+		"Only shorten lines that were added or changed in the unified diff read from stdin").
+		Default("false").Bool()
 	dotFile = kingpin.Flag(
 		"dot-file",
 		"Path to dot representation of AST graph").Default("").String()
 	dryRun = kingpin.Flag(
 		"dry-run",
 		"Show diffs without writing anything").Default("false").Bool()
+	gofumptExtra = kingpin.Flag(
+		"gofumpt-extra",
+		"Enable gofumpt's extra rules when using it as the base formatter").
+		Default("false").Bool()
+	gofumptLangVersion = kingpin.Flag(
+		"gofumpt-lang-version",
+		"Go version of the code for gofumpt, e.g. go1.22").Default("").String()
+	gofumptModulePath = kingpin.Flag(
+		"gofumpt-module-path",
+		"Module path of the code for gofumpt").Default("").String()
+	hoistIfInit = kingpin.Flag(
+		"hoist-if-init",
+		"Move the init statements of long if statements before them when possible").
+		Default("false").Bool()
 	ignoreGenerated = kingpin.Flag(
 		"ignore-generated",
 		"Ignore generated go files").Default("true").Bool()
 	ignoredDirs = kingpin.Flag(
 		"ignored-dirs",
 		"Directories to ignore").Default("vendor", "node_modules", ".git").Strings()
+	jobs = kingpin.Flag(
+		"jobs",
+		"Number of files to process in parallel; defaults to GOMAXPROCS").Short('j').
+		Default(strconv.Itoa(runtime.GOMAXPROCS(0))).Int()
 	keepAnnotations = kingpin.Flag(
 		"keep-annotations",
 		"Keep shortening annotations in final output").Default("false").Bool()
+	keepGoing = kingpin.Flag(
+		"keep-going",
+		"Keep processing other files after an error and print a summary at the end").
+		Short('k').Default("false").Bool()
+	lineRanges = kingpin.Flag(
+		"lines",
+		"Only shorten lines in this start:end range (can be repeated)").Strings()
 	listFiles = kingpin.Flag(
 		"list-files",
 		"List files that would be reformatted by this tool").Short('l').Default("false").Bool()
+	localPrefixes = kingpin.Flag(
+		"local-prefixes",
+		"Comma-separated import path prefixes that goimports puts after the third-party "+
+			"imports").Default("").String()
 	maxLen = kingpin.Flag(
 		"max-len",
 		"Target maximum line length").Short('m').Default("100").Int()
+	outputFormat = kingpin.Flag(
+		"output",
+		"Output format; json and edits write a stream of per-file results or text edits "+
+			"instead of the source").Default(textOutput).Enum(textOutput, jsonOutput, editsOutput)
 	profile = kingpin.Flag(
 		"profile",
 		"Path to profile output").Default("").String()
+	selectionRange = kingpin.Flag(
+		"range",
+		"Only shorten the lines in this start:end line range or #start-#end byte range").
+		Default("").String()
 	reformatTags = kingpin.Flag(
 		"reformat-tags",
 		"Reformat struct tags").Default("true").Bool()
+	reportFormat = kingpin.Flag(
+		"report",
+		"Also write a report of the lines that are still too long after shortening in "+
+			"this format").Enum(sarifFormat)
+	reportPath = kingpin.Flag(
+		"report-path",
+		"Path to write the report to").Default("golines.sarif").String()
 	shortenComments = kingpin.Flag(
 		"shorten-comments",
 		"Shorten single-line comments").Default("false").Bool()
+	splitStrings = kingpin.Flag(
+		"split-strings",
+		"Split long string literals into concatenations").Default("false").Bool()
 	tabLen = kingpin.Flag(
 		"tab-len",
 		"Length of a tab").Short('t').Default("4").Int()
@@ -73,15 +155,41 @@ var (
 		"write-output",
 		"Write output to source instead of stdout").Short('w').Default("false").Bool()
 
+	// Commands
+	formatCmd = kingpin.Command(
+		"format",
+		"Shorten the lines in the provided files, or stdin if none are provided (default)",
+	).Default()
+	lspCmd = kingpin.Command(
+		"lsp",
+		"Run a language server that provides formatting over stdin and stdout",
+	)
+
 	// Args
-	paths = kingpin.Arg(
+	paths = formatCmd.Arg(
 		"paths",
 		"Paths to format",
 	).Strings()
+
+	// Names of the flags that were explicitly set on the command line; these take
+	// precedence over the values in config files
+	flagsSetByUser = map[string]bool{}
 )
 
 func main() {
-	kingpin.Parse()
+	command := kingpin.Parse()
+
+	// Parse again to find out which flags were set explicitly (as opposed to via defaults)
+	parseContext, err := kingpin.CommandLine.ParseContext(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, element := range parseContext.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			flagsSetByUser[flag.Model().Name] = true
+		}
+	}
+
 	if *debug {
 		log.SetLevel(log.DebugLevel)
 	} else {
@@ -109,128 +217,223 @@ func main() {
 		ForceFormatting: true,
 	})
 
-	err := run()
+	if command == lspCmd.FullCommand() {
+		err = runLSP()
+	} else {
+		err = run()
+	}
+	if errors.Is(err, errCheckFailed) {
+		log.Error(err)
+		os.Exit(exitCodeCheckFailed)
+	} else if err != nil {
+		log.Error(err)
+		os.Exit(exitCodeError)
+	}
+}
+
+// runLSP runs a language server over stdin and stdout. The settings from the flags and
+// the config file for the current directory are used as the defaults, which the client can
+// then override.
+func runLSP() error {
+	config, err := newConfigResolver().resolve(".")
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+
+	return lsp.NewServer(os.Stdin, os.Stdout, config.shortenerConfig).Run()
 }
 
 func run() error {
-	config := ShortenerConfig{
-		MaxLen:           *maxLen,
-		TabLen:           *tabLen,
-		KeepAnnotations:  *keepAnnotations,
-		ShortenComments:  *shortenComments,
-		ReformatTags:     *reformatTags,
-		IgnoreGenerated:  *ignoreGenerated,
-		DotFile:          *dotFile,
-		BaseFormatterCmd: *baseFormatterCmd,
-		ChainSplitDots:   *chainSplitDots,
+	resolver := newConfigResolver()
+
+	selection, err := newLineSelection()
+	if err != nil {
+		return err
+	}
+
+	var report *sarifReport
+	if *reportFormat == sarifFormat {
+		if len(*paths) == 0 {
+			return errors.New("--report requires paths to format")
+		}
+		report = &sarifReport{}
+	}
+
+	summary := &runSummary{}
+	handleResult := func(task fileTask, result fileResult) error {
+		summary.numProcessed++
+
+		err := result.err
+		if err == nil {
+			if result.generated {
+				summary.numGenerated++
+			} else {
+				if !bytes.Equal(result.contents, result.result) {
+					summary.numChanged++
+				}
+				err = handleOutput(task.path, result.contents, result.result)
+
+				if report != nil {
+					report.add(
+						task.path,
+						result.result,
+						task.shortener.LongLines(result.result),
+					)
+				}
+			}
+		}
+
+		var writeErr error
+		switch *outputFormat {
+		case jsonOutput:
+			writeErr = writeJSONResult(os.Stdout, task, result, err)
+		case editsOutput:
+			writeErr = writeEditsResult(os.Stdout, task, result, err)
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+
+		// In the JSON-based output modes, errors are reported per file in the output stream
+		if err != nil && (*keepGoing || isStreamOutput()) {
+			log.Debugf("error processing %s: %+v", task.path, err)
+			summary.failures = append(summary.failures, fileFailure{path: task.path, err: err})
+			return nil
+		}
+		return err
 	}
-	shortener := NewShortener(config)
 
 	if len(*paths) == 0 {
-		// Read input from stdin
-		contents, err := io.ReadAll(os.Stdin)
+		// Read input from stdin, using the config that applies to the current directory
+		config, err := resolver.resolve(".")
 		if err != nil {
 			return err
 		}
 
-		result, err := shortener.Shorten(contents)
+		contents, err := io.ReadAll(os.Stdin)
 		if err != nil {
 			return err
 		}
-		err = handleOutput("", contents, result)
+
+		task := fileTask{
+			config:    config,
+			shortener: resolver.shortener(config),
+			selection: selection,
+		}
+
+		err = handleResult(task, shortenContents(task, contents))
 		if err != nil {
 			return err
 		}
 	} else {
 		// Read inputs from paths provided in arguments
-		for _, path := range *paths {
-			switch info, err := os.Stat(path); {
-			case err != nil:
-				return err
-			case info.IsDir():
-				// Path is a directory- walk it
-				err = filepath.Walk(
-					path,
-					func(subPath string, subInfo os.FileInfo, err error) error {
-						if err != nil {
-							return err
-						}
-
-						components := strings.Split(subPath, "/")
-						for _, component := range components {
-							for _, ignoredDir := range *ignoredDirs {
-								if component == ignoredDir {
-									return filepath.SkipDir
-								}
-							}
-						}
-
-						if !subInfo.IsDir() && strings.HasSuffix(subPath, ".go") {
-							// Shorten file and generate output
-							contents, result, err := processFile(shortener, subPath)
-							if err != nil {
-								return err
-							}
-							err = handleOutput(subPath, contents, result)
-							if err != nil {
-								return err
-							}
-						}
-
-						return nil
-					},
-				)
-				if err != nil {
-					return err
-				}
-			default:
-				// Path is a file
-				contents, result, err := processFile(shortener, path)
-				if err != nil {
-					return err
-				}
-				err = handleOutput(path, contents, result)
-				if err != nil {
-					return err
-				}
-			}
+		tasks, err := collectFiles(resolver, selection, *paths)
+		if err != nil {
+			return err
 		}
+
+		err = processFiles(tasks, *jobs, handleResult)
+		if err != nil {
+			return err
+		}
+
+		if *keepGoing {
+			summary.print(os.Stderr)
+		}
+	}
+
+	if report != nil {
+		err = report.writeFile(*reportPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(summary.failures) > 0 {
+		return fmt.Errorf("%d file(s) could not be processed", len(summary.failures))
+	}
+
+	if *check && summary.numChanged > 0 {
+		return fmt.Errorf(
+			"%w: %d file(s) would be reformatted",
+			errCheckFailed,
+			summary.numChanged,
+		)
 	}
 
 	return nil
 }
 
-// processFile uses the provided Shortener instance to shorten the lines
-// in a file. It returns the original contents (useful for debugging), the
-// shortened version, and an error.
-func processFile(shortener *Shortener, path string) ([]byte, []byte, error) {
+// processFile shortens the lines in a file according to the provided task. The result
+// includes the original contents (useful for debugging) and the shortened version, unless
+// the file is skipped because it's generated.
+func processFile(task fileTask) fileResult {
+	path := task.path
+	config := task.config
+
 	_, fileName := filepath.Split(path)
-	if *ignoreGenerated && strings.HasPrefix(fileName, "generated_") {
-		return nil, nil, nil
+	if config.shortenerConfig.IgnoreGenerated && strings.HasPrefix(fileName, "generated_") {
+		return fileResult{generated: true}
 	}
 
 	log.Debugf("processing file %s", path)
 
 	contents, err := os.ReadFile(path)
 	if err != nil {
-		return nil, nil, err
+		return fileResult{err: err}
+	}
+
+	if config.shortenerConfig.IgnoreGenerated && shorten.IsGenerated(contents) {
+		return fileResult{generated: true}
+	}
+
+	return shortenContents(task, contents)
+}
+
+// shortenContents shortens the provided file contents according to the provided task.
+func shortenContents(task fileTask, contents []byte) fileResult {
+	ranges, restricted := task.selection.forFile(task.path, contents)
+	if restricted && len(ranges) == 0 {
+		// None of the lines in this file should be shortened
+		return fileResult{contents: contents, result: contents}
 	}
 
-	result, err := shortener.Shorten(contents)
-	return contents, result, err
+	result, err := task.shortener.WithFilename(task.path).ShortenDetailed(contents, ranges)
+	return fileResult{
+		contents: contents,
+		result:   result.Contents,
+		rounds:   result.Rounds,
+		err:      err,
+	}
 }
 
 // handleOutput generates output according to the value of the tool's
 // flags; depending on the latter, the output might be written over
 // the source file, printed to stdout, etc.
 func handleOutput(path string, contents []byte, result []byte) error {
-	if contents == nil {
+	if isStreamOutput() {
+		// The JSON results are written separately; only update the file if requested
+		if *writeOutput {
+			return writeFile(path, contents, result)
+		}
+
+		return nil
+	} else if *check {
+		if !bytes.Equal(contents, result) {
+			if path == "" {
+				fmt.Println("<standard input>")
+			} else {
+				fmt.Println(path)
+			}
+
+			if *dryRun {
+				return shorten.PrettyDiff(path, contents, result)
+			}
+		}
+
 		return nil
 	} else if *dryRun {
-		return PrettyDiff(path, contents, result)
+		return shorten.PrettyDiff(path, contents, result)
 	} else if *listFiles {
 		if !bytes.Equal(contents, result) {
 			fmt.Println(path)
@@ -238,25 +441,30 @@ func handleOutput(path string, contents []byte, result []byte) error {
 
 		return nil
 	} else if *writeOutput {
-		if path == "" {
-			return errors.New("no path to write out to")
-		}
+		return writeFile(path, contents, result)
+	}
 
-		info, err := os.Stat(path)
-		if err != nil {
-			return err
-		}
+	fmt.Print(string(result))
+	return nil
 
-		if bytes.Equal(contents, result) {
-			log.Debugf("contents unchanged, skipping write")
-			return nil
-		}
+}
 
-		log.Debugf("contents changed, writing output to %s", path)
-		return os.WriteFile(path, result, info.Mode())
+// writeFile writes the shortened result over the source file if its contents changed.
+func writeFile(path string, contents []byte, result []byte) error {
+	if path == "" {
+		return errors.New("no path to write out to")
 	}
 
-	fmt.Print(string(result))
-	return nil
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(contents, result) {
+		log.Debugf("contents unchanged, skipping write")
+		return nil
+	}
 
+	log.Debugf("contents changed, writing output to %s", path)
+	return os.WriteFile(path, result, info.Mode())
 }
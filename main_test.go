@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -8,6 +11,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/segmentio/golines/shorten"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -152,10 +156,309 @@ func TestRunListFiles(t *testing.T) {
 	)
 }
 
+func TestRunCheck(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths = &[]string{}
+	writeOutput = boolPtr(true)
+	check = boolPtr(true)
+	defer func() {
+		check = boolPtr(false)
+	}()
+
+	writeTestFiles(t, map[string]string{"test3.go": "package main\n"}, true, tmpDir)
+
+	// Files that don't need to be shortened pass the check
+	output, err := captureStdout(t, run)
+	assert.Nil(t, err)
+	assert.Equal(t, "", output)
+
+	writeTestFiles(t, testFiles, true, tmpDir)
+
+	output, err = captureStdout(t, run)
+	assert.ErrorIs(t, err, errCheckFailed)
+
+	expectedPaths := []string{
+		filepath.Join(tmpDir, "test1.go"),
+		filepath.Join(tmpDir, "test2.go"),
+	}
+
+	actualPaths := strings.Split(strings.TrimSpace(output), "\n")
+	sort.Strings(actualPaths)
+	assert.Equal(t, expectedPaths, actualPaths)
+
+	// Files should not be modified in check mode, even with writeOutput set
+	for name, contents := range testFiles {
+		bytes, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal("Unexpected error reading test file", err)
+		}
+
+		assert.Equal(t, contents, string(bytes))
+	}
+}
+
+func TestRunKeepGoing(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths = &[]string{tmpDir}
+	writeOutput = boolPtr(true)
+	listFiles = boolPtr(false)
+	ignoreGenerated = boolPtr(true)
+
+	updatedTestFiles := map[string]string{
+		// File that can't be parsed
+		"test0.go": "package main\n\nfunc main() {\n",
+
+		"test1.go": testFiles["test1.go"],
+		"test2.go": testFiles["test2.go"],
+
+		// Generated file
+		"test4.go": "// Code generated by hand. DO NOT EDIT.\n\npackage main\n",
+	}
+	writeTestFiles(t, updatedTestFiles, false, tmpDir)
+
+	// Without keepGoing set, processing stops at the invalid file
+	err = run()
+	assert.NotNil(t, err)
+
+	contents, err := os.ReadFile(filepath.Join(tmpDir, "test1.go"))
+	if err != nil {
+		t.Fatal("Unexpected error reading test file", err)
+	}
+	assert.Equal(t, updatedTestFiles["test1.go"], string(contents))
+
+	keepGoing = boolPtr(true)
+	defer func() {
+		keepGoing = boolPtr(false)
+	}()
+
+	err = run()
+	assert.NotNil(t, err)
+
+	// The files after the invalid one should now be processed
+	for _, name := range []string{"test1.go", "test2.go"} {
+		contents, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatal("Unexpected error reading test file", err)
+		}
+
+		assert.NotEqual(
+			t,
+			strings.TrimSpace(updatedTestFiles[name]),
+			strings.TrimSpace(string(contents)),
+		)
+	}
+}
+
+func TestRunJSONOutput(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths = &[]string{tmpDir}
+	writeOutput = boolPtr(false)
+	listFiles = boolPtr(false)
+	outputFormat = stringPtr(jsonOutput)
+	setLengthFlags(t, 100, 4)
+	defer func() {
+		outputFormat = stringPtr(textOutput)
+	}()
+
+	updatedTestFiles := map[string]string{
+		"test1.go": testFiles["test1.go"],
+
+		// File that can't be parsed
+		"test2.go": "package main\n\nfunc main() {\n",
+
+		// File that doesn't need to be shortened
+		"test3.go": "package main\n",
+	}
+	writeTestFiles(t, updatedTestFiles, false, tmpDir)
+
+	// Errors are reported in the output, but processing continues
+	output, err := captureStdout(t, run)
+	assert.NotNil(t, err)
+
+	results := []jsonResult{}
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for decoder.More() {
+		result := jsonResult{}
+		if err := decoder.Decode(&result); err != nil {
+			t.Fatal("Unexpected error decoding output", err)
+		}
+		results = append(results, result)
+	}
+
+	if assert.Equal(t, 3, len(results)) {
+		assert.Equal(t, filepath.Join(tmpDir, "test1.go"), results[0].Path)
+		assert.True(t, results[0].Changed)
+		assert.Greater(t, results[0].Rounds, 0)
+		assert.Equal(
+			t,
+			[]shorten.LongLine{{Line: 6, Column: 98, Length: 113, MaxLen: 100}},
+			results[0].LongLinesBefore,
+		)
+		assert.Equal(t, []shorten.LongLine{}, results[0].LongLinesAfter)
+		assert.Equal(t, "", results[0].Error)
+
+		assert.Equal(t, filepath.Join(tmpDir, "test2.go"), results[1].Path)
+		assert.False(t, results[1].Changed)
+		assert.NotEqual(t, "", results[1].Error)
+
+		assert.Equal(t, filepath.Join(tmpDir, "test3.go"), results[2].Path)
+		assert.False(t, results[2].Changed)
+		assert.Equal(t, []shorten.LongLine{}, results[2].LongLinesBefore)
+	}
+
+	// Without writeOutput set, inputs should be unchanged
+	contents, err := os.ReadFile(filepath.Join(tmpDir, "test1.go"))
+	if err != nil {
+		t.Fatal("Unexpected error reading test file", err)
+	}
+	assert.Equal(t, updatedTestFiles["test1.go"], string(contents))
+}
+
+func TestRunEditsOutput(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths = &[]string{tmpDir}
+	writeOutput = boolPtr(false)
+	listFiles = boolPtr(false)
+	outputFormat = stringPtr(editsOutput)
+	defer func() {
+		outputFormat = stringPtr(textOutput)
+	}()
+
+	writeTestFiles(t, map[string]string{"test1.go": testFiles["test1.go"]}, false, tmpDir)
+
+	output, err := captureStdout(t, run)
+	assert.Nil(t, err)
+
+	result := editsResult{}
+	err = json.Unmarshal([]byte(output), &result)
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "test1.go"), result.Path)
+
+	// Applying the edits should give the same result as the default output mode
+	outputFormat = stringPtr(textOutput)
+	expected, err := captureStdout(t, run)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		expected,
+		string(shorten.ApplyEdits([]byte(testFiles["test1.go"]), result.Edits)),
+	)
+}
+
+func TestRunSARIFReport(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	paths = &[]string{tmpDir}
+	writeOutput = boolPtr(true)
+	listFiles = boolPtr(false)
+	reportFormat = stringPtr(sarifFormat)
+	reportPath = stringPtr(filepath.Join(tmpDir, "golines.sarif"))
+	setLengthFlags(t, 100, 4)
+	defer func() {
+		reportFormat = stringPtr("")
+	}()
+
+	updatedTestFiles := map[string]string{
+		"test1.go": testFiles["test1.go"],
+
+		// File with a line that can't be shortened
+		"test2.go": "package main\n\nvar x = \"" + strings.Repeat("x", 100) + "\"\n",
+	}
+	writeTestFiles(t, updatedTestFiles, false, tmpDir)
+
+	err = run()
+	assert.Nil(t, err)
+
+	contents, err := os.ReadFile(*reportPath)
+	if err != nil {
+		t.Fatal("Unexpected error reading report", err)
+	}
+
+	report := sarifLog{}
+	err = json.Unmarshal(contents, &report)
+	assert.Nil(t, err)
+	assert.Equal(t, "2.1.0", report.Version)
+
+	if assert.Equal(t, 1, len(report.Runs)) &&
+		assert.Equal(t, 1, len(report.Runs[0].Results)) {
+		location := report.Runs[0].Results[0].Locations[0].PhysicalLocation
+		assert.Equal(
+			t,
+			filepath.ToSlash(filepath.Join(tmpDir, "test2.go")),
+			location.ArtifactLocation.URI,
+		)
+		assert.Equal(
+			t,
+			sarifRegion{StartLine: 3, StartColumn: 101, EndColumn: 111},
+			location.Region,
+		)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	summary := &runSummary{
+		numProcessed: 4,
+		numChanged:   2,
+		numGenerated: 1,
+		failures: []fileFailure{
+			{path: "test3.go", err: errors.New("parse error")},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	summary.print(out)
+
+	assert.Equal(
+		t,
+		"files processed: 4\nfiles changed: 2\nfiles skipped as generated: 1\n"+
+			"files failed: 1\n\ttest3.go: parse error\n",
+		out.String(),
+	)
+}
+
+// setLengthFlags sets the max-len and tab-len flags for the duration of the provided test.
+func setLengthFlags(t *testing.T, maxLenValue int, tabLenValue int) {
+	origMaxLen, origTabLen := maxLen, tabLen
+	t.Cleanup(func() {
+		maxLen, tabLen = origMaxLen, origTabLen
+	})
+
+	maxLen = intPtr(maxLenValue)
+	tabLen = intPtr(tabLenValue)
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
 
+func stringPtr(s string) *string {
+	return &s
+}
+
 func writeTestFiles(
 	t *testing.T,
 	fileContents map[string]string,
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/segmentio/golines/shorten"
+)
+
+// fileTask is a single file to be shortened, along with the settings that apply to it.
+type fileTask struct {
+	path      string
+	config    runConfig
+	shortener *shorten.Shortener
+
+	// If set, only the selected lines are shortened
+	selection *lineSelection
+}
+
+// fileResult is the outcome of processing a fileTask.
+type fileResult struct {
+	contents  []byte
+	result    []byte
+	rounds    int
+	generated bool
+	err       error
+}
+
+// fileFailure is an error that occurred while processing a file.
+type fileFailure struct {
+	path string
+	err  error
+}
+
+// runSummary stores the statistics about a run that are printed in keep-going mode.
+type runSummary struct {
+	numProcessed int
+	numChanged   int
+	numGenerated int
+	failures     []fileFailure
+}
+
+// print writes a human-readable version of the summary to the provided writer.
+func (r *runSummary) print(w io.Writer) {
+	fmt.Fprintf(w, "files processed: %d\n", r.numProcessed)
+	fmt.Fprintf(w, "files changed: %d\n", r.numChanged)
+	fmt.Fprintf(w, "files skipped as generated: %d\n", r.numGenerated)
+	fmt.Fprintf(w, "files failed: %d\n", len(r.failures))
+
+	for _, failure := range r.failures {
+		fmt.Fprintf(w, "\t%s: %+v\n", failure.path, failure.err)
+	}
+}
+
+// collectFiles expands the provided paths into the list of go files to process, walking
+// any directories and skipping the ignored ones. The settings for each file are resolved
+// up front so that the configResolver doesn't need to be shared between
+// goroutines. Files are returned in a stable order: the order of the paths, then lexical
+// order within each directory.
+func collectFiles(
+	resolver *configResolver,
+	selection *lineSelection,
+	paths []string,
+) ([]fileTask, error) {
+	tasks := []fileTask{}
+
+	addTask := func(path string, config runConfig) {
+		tasks = append(
+			tasks,
+			fileTask{
+				path:      path,
+				config:    config,
+				shortener: resolver.shortener(config),
+				selection: selection,
+			},
+		)
+	}
+
+	for _, path := range paths {
+		switch info, err := os.Stat(path); {
+		case err != nil:
+			return nil, err
+		case info.IsDir():
+			// Path is a directory- walk it
+			err = filepath.Walk(
+				path,
+				func(subPath string, subInfo os.FileInfo, err error) error {
+					if err != nil {
+						return err
+					}
+
+					// Resolve the settings for each file (and directory) separately
+					// since nested config files can override them
+					config, err := resolver.resolve(subPath)
+					if err != nil {
+						return err
+					}
+
+					components := strings.Split(subPath, "/")
+					for _, component := range components {
+						for _, ignoredDir := range config.ignoredDirs {
+							if component == ignoredDir {
+								return filepath.SkipDir
+							}
+						}
+					}
+
+					if !subInfo.IsDir() && strings.HasSuffix(subPath, ".go") {
+						addTask(subPath, config)
+					}
+
+					return nil
+				},
+			)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			// Path is a file
+			config, err := resolver.resolve(path)
+			if err != nil {
+				return nil, err
+			}
+			addTask(path, config)
+		}
+	}
+
+	return tasks, nil
+}
+
+// processFiles shortens the provided files using a pool of numWorkers goroutines. The
+// handleResult function is called for each file in the same order as the tasks, regardless
+// of the order in which the workers finish, so that the output is deterministic. Processing
+// stops at the first error returned by handleResult.
+func processFiles(
+	tasks []fileTask,
+	numWorkers int,
+	handleResult func(task fileTask, result fileResult) error,
+) error {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+
+	// Each task gets its own buffered channel so that workers never block on sending
+	// results, even if the outputs for earlier tasks haven't been handled yet
+	results := make([]chan fileResult, len(tasks))
+	for t := range tasks {
+		results[t] = make(chan fileResult, 1)
+	}
+
+	taskIndices := make(chan int)
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	go func() {
+		defer close(taskIndices)
+
+		for t := range tasks {
+			select {
+			case taskIndices <- t:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for t := range taskIndices {
+				results[t] <- processFile(tasks[t])
+			}
+		}()
+	}
+
+	for t, task := range tasks {
+		err := handleResult(task, <-results[t])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "go")
+	if err != nil {
+		t.Fatal("Unexpected error creating temp dir", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fileContents := map[string]string{}
+	for i := 0; i < 20; i++ {
+		fileContents[fmt.Sprintf("test%02d.go", i)] = testFiles["test1.go"]
+	}
+	writeTestFiles(t, fileContents, false, tmpDir)
+
+	setLengthFlags(t, 100, 4)
+	resolver := newConfigResolver()
+	tasks, err := collectFiles(resolver, nil, []string{tmpDir})
+	assert.Nil(t, err)
+	assert.Equal(t, 20, len(tasks))
+
+	// Outputs are generated in the same order as the tasks
+	outputPaths := []string{}
+	err = processFiles(
+		tasks,
+		4,
+		func(task fileTask, result fileResult) error {
+			assert.Nil(t, result.err)
+			assert.NotEqual(t, string(result.contents), string(result.result))
+			outputPaths = append(outputPaths, task.path)
+			return nil
+		},
+	)
+	assert.Nil(t, err)
+
+	expectedPaths := []string{}
+	for i := 0; i < 20; i++ {
+		expectedPaths = append(
+			expectedPaths,
+			filepath.Join(tmpDir, fmt.Sprintf("test%02d.go", i)),
+		)
+	}
+	assert.Equal(t, expectedPaths, outputPaths)
+
+	// Processing stops at the first error
+	numOutputs := 0
+	err = processFiles(
+		tasks,
+		4,
+		func(task fileTask, result fileResult) error {
+			numOutputs++
+			if numOutputs == 5 {
+				return fmt.Errorf("error on %s", task.path)
+			}
+			return nil
+		},
+	)
+	assert.NotNil(t, err)
+	assert.Equal(t, 5, numOutputs)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/segmentio/golines/shorten"
+)
+
+const (
+	// Output formats supported by the --output flag
+	textOutput  = "text"
+	jsonOutput  = "json"
+	editsOutput = "edits"
+
+	// Report formats supported by the --report flag
+	sarifFormat = "sarif"
+
+	// ID of the SARIF rule for lines that are still too long after shortening
+	sarifRuleID = "line-too-long"
+)
+
+// isStreamOutput returns whether the output format is one that writes a stream of JSON
+// records instead of the source.
+func isStreamOutput() bool {
+	return *outputFormat == jsonOutput || *outputFormat == editsOutput
+}
+
+// jsonResult is the record written for each file in JSON output mode.
+type jsonResult struct {
+	Path            string             `json:"path"`
+	Changed         bool               `json:"changed"`
+	Generated       bool               `json:"generated,omitempty"`
+	Rounds          int                `json:"rounds"`
+	LongLinesBefore []shorten.LongLine `json:"longLinesBefore"`
+	LongLinesAfter  []shorten.LongLine `json:"longLinesAfter"`
+	Error           string             `json:"error,omitempty"`
+}
+
+// writeJSONResult writes the result of processing a file as a single line of JSON. The
+// provided error, if any, takes precedence over the one in the result since it also covers
+// failures that happened while handling the output.
+func writeJSONResult(w io.Writer, task fileTask, result fileResult, err error) error {
+	record := jsonResult{
+		Path:            displayPath(task.path),
+		Generated:       result.generated,
+		Rounds:          result.rounds,
+		LongLinesBefore: []shorten.LongLine{},
+		LongLinesAfter:  []shorten.LongLine{},
+	}
+
+	if result.contents != nil {
+		record.LongLinesBefore = task.shortener.LongLines(result.contents)
+	}
+	if result.result != nil {
+		record.Changed = !bytes.Equal(result.contents, result.result)
+		record.LongLinesAfter = task.shortener.LongLines(result.result)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	return json.NewEncoder(w).Encode(record)
+}
+
+// editsResult is the record written for each file in edits output mode.
+type editsResult struct {
+	Path  string             `json:"path"`
+	Edits []shorten.TextEdit `json:"edits"`
+	Error string             `json:"error,omitempty"`
+}
+
+// writeEditsResult writes the edits needed to shorten a file as a single line of JSON. As
+// with writeJSONResult, the provided error takes precedence over the one in the result.
+func writeEditsResult(w io.Writer, task fileTask, result fileResult, err error) error {
+	record := editsResult{
+		Path:  displayPath(task.path),
+		Edits: []shorten.TextEdit{},
+	}
+
+	if result.result != nil {
+		record.Edits = shorten.ComputeEdits(result.contents, result.result)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	return json.NewEncoder(w).Encode(record)
+}
+
+// displayPath returns the path to use for a file in the output, which is a placeholder in
+// the case of stdin.
+func displayPath(path string) string {
+	if path == "" {
+		return "<standard input>"
+	}
+	return path
+}
+
+// sarifLog is the top-level object in a SARIF 2.1.0 file. Only the subset of the format
+// that's needed to report long lines is included here.
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html for the full spec.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// sarifReport collects the lines that are still longer than the max length after
+// shortening so that they can be written out as a SARIF file at the end of a run.
+type sarifReport struct {
+	results []sarifResult
+}
+
+// add adds the provided long lines in the file at the provided path to the report.
+func (r *sarifReport) add(path string, contents []byte, longLines []shorten.LongLine) {
+	lines := bytes.Split(contents, []byte("\n"))
+
+	for _, longLine := range longLines {
+		r.results = append(
+			r.results,
+			sarifResult{
+				RuleID: sarifRuleID,
+				Level:  "warning",
+				Message: sarifMessage{
+					Text: fmt.Sprintf(
+						"Line is %d characters long, which exceeds the maximum of %d",
+						longLine.Length,
+						longLine.MaxLen,
+					),
+				},
+				Locations: []sarifLocation{
+					{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: sarifArtifactLocation{
+								URI: filepath.ToSlash(path),
+							},
+							Region: sarifRegion{
+								StartLine:   longLine.Line,
+								StartColumn: longLine.Column,
+								EndColumn: len(
+									bytes.Runes(lines[longLine.Line-1]),
+								) + 1,
+							},
+						},
+					},
+				},
+			},
+		)
+	}
+}
+
+// write writes the report in SARIF 2.1.0 format to the provided writer.
+func (r *sarifReport) write(w io.Writer) error {
+	results := r.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+
+	sarif := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "golines",
+						Version:        version,
+						InformationURI: "https://github.com/segmentio/golines",
+						Rules: []sarifRule{
+							{
+								ID: sarifRuleID,
+								ShortDescription: sarifMessage{
+									Text: "Line is longer than the maximum length",
+								},
+							},
+						},
+					},
+				},
+				// Columns are counted in characters rather than UTF-16 code units
+				ColumnKind: "unicodeCodePoints",
+				Results:    results,
+			},
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarif)
+}
+
+// writeFile writes the report to a file at the provided path.
+func (r *sarifReport) writeFile(path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = r.write(out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
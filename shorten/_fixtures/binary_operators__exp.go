@@ -0,0 +1,51 @@
+package fixtures
+
+import (
+	"fmt"
+)
+
+func testBinaryOperators() {
+	z := argument1 + argument2 + fmt.Sprintf(
+		"This is a really long statement that should be broken up %s %s %s",
+		argument1,
+		argument2,
+		argument3,
+	)
+	y := "hello this is a big string" || "this is a small string" || "the smallest string" ||
+		"this is another big string" ||
+		"this is an even bigger string >>>"
+}
+
+func testArithmetic() {
+	total := firstValue*firstMultiplier + secondValue*secondMultiplier +
+		thirdValue*thirdMultiplier +
+		fourth
+	product := firstVeryLongValueName * secondVeryLongValueName * thirdVeryLongValueName *
+		fourthValue
+	return total ==
+		firstVeryLongValueName*secondVeryLongValueName+thirdVeryLongValueName-fourthValue
+}
+
+func testComparisons() bool {
+	if firstVeryLongValueName+secondVeryLongValueName >
+		thirdVeryLongValueName-fourthVeryLongValueName {
+		return true
+	}
+
+	return len(someCollectionWithALongName) == len(anotherCollectionWithALongName) &&
+		isValid(argument1)
+}
+
+func testConcatenation() string {
+	message := "The first part of the message " + firstVariable + " and the second part " +
+		secondVariable
+	other := "The first part of the other message " + firstVariable + " and the second part " +
+		format(secondVariable)
+	last := "The first part of the last message " + firstVariable + " and the second part " +
+		format(
+			secondVariable,
+			thirdVariable,
+			"a fourth argument that's long enough to need splitting",
+		)
+	return message + other + last
+}
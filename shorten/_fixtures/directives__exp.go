@@ -0,0 +1,112 @@
+package fixtures
+
+import (
+	"fmt"
+	"strings"
+)
+
+//golines:ignore
+var table = map[string][]int{"first": {1, 2, 3}, "second": {4, 5, 6}, "third": {7, 8, 9}, "fourth": {10}}
+
+var notIgnored = map[string][]int{
+	"first":  {1, 2, 3},
+	"second": {4, 5, 6},
+	"third":  {7, 8, 9},
+	"fourth": {10},
+}
+
+func directives() {
+	//golines:ignore the arguments are laid out like this on purpose
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+
+	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+
+	//golines:off
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+
+	// This is a really long comment that would normally be shortened but is in an off region, so it isn't.
+	type MyStruct struct {
+		Field1 string `json:"field1" info:"something"`
+		Field2 string `json:"field2 long value" info:"third thing"`
+	}
+	//golines:on
+
+	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+}
+
+//golines:off
+
+func offFunction(argument1 string, argument2 string, argument3 string, argument4 string, argument5 string) error {
+	return nil
+}
+
+//golines:on
+
+func onFunction(
+	argument1 string,
+	argument2 string,
+	argument3 string,
+	argument4 string,
+	argument5 string,
+) error {
+	return nil
+}
+
+//golines:max-len=140
+func buildQuery(table string, columns []string, conditions map[string]string, limit int) string {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d", strings.Join(columns, ", "), table, where, limit)
+	return query
+}
+
+func buildOtherQuery(
+	table string,
+	columns []string,
+	conditions map[string]string,
+	limit int,
+) string {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s LIMIT %d",
+		strings.Join(columns, ", "),
+		table,
+		where,
+		limit,
+	)
+
+	//golines:max-len=120
+	otherQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s LIMIT %d", strings.Join(columns, ", "), table, where, limit)
+
+	return query + otherQuery
+}
+
+func trailingDirective() {
+	fmt.Println("the directive below isn't followed by anything in this block")
+	//golines:ignore
+}
+
+func afterTrailingDirective() {
+	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+}
@@ -0,0 +1,52 @@
+package fixtures
+
+type VeryLongTypeNameForTheResult struct{}
+
+// Splitting the params is enough here
+func shortResults(
+	aReallyLongName string,
+	anotherLongName string,
+	aThirdLongName string,
+) (int, error) {
+	return 0, nil
+}
+
+// The results are still too long after the params are split
+func longResults(
+	aReallyLongName string,
+	anotherLongName string,
+) (
+	result *VeryLongTypeNameForTheResult,
+	metadata map[string]interface{},
+	statusCode int,
+	err error,
+) {
+	return nil, nil, 0, nil
+}
+
+// There are no params to split
+func noParams() (
+	result *VeryLongTypeNameForTheResult,
+	metadataForResult map[string]interface{},
+	statusCode int,
+	err error,
+) {
+	return nil, nil, 0, nil
+}
+
+func (v *VeryLongTypeNameForTheResult) method() (
+	*VeryLongTypeNameForTheResult,
+	map[string]interface{},
+	int,
+	error,
+) {
+	return nil, nil, 0, nil
+}
+
+// A single unnamed result can't be split
+func singleResult(
+	aReallyLongName string,
+	anotherLongName string,
+) map[string]*VeryLongTypeNameForTheResult {
+	return nil
+}
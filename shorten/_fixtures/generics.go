@@ -0,0 +1,44 @@
+package main
+
+func SumIntsOrFloats[K comparable, V int64 | float64](m map[K]V, longArgument1 int, longArgument2 int, longArgument3 int) V {
+	var s V
+	for _, v := range m {
+		s += v
+	}
+	return s
+}
+
+func SumIntsOrFloats2[K comparable, V int64 | float64](m map[K]V) V {
+	var s V
+	for _, v := range m {
+		s += v
+	}
+	return s
+}
+
+func Map[K comparable, V any, R SomeLongConstraintName[K, V], S AnotherLongConstraintName[K, V], T any](m map[K]V, f func(K, V) R) []R {
+	return nil
+}
+
+func MapWithLongParams[K comparable, V any](inputMap map[K]V, mappingFunction func(K, V) V, filterFunction func(K, V) bool) map[K]V {
+	return nil
+}
+
+func Constrained[Number ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64](n Number) Number {
+	return n
+}
+
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+type Cache[KeyType comparable, ValueType any, EventType fmt.Stringer, ConstraintType SomeLongConstraintName[KeyType, ValueType]] struct {
+	values map[KeyType]ValueType
+}
+
+func instantiations() {
+	cache := NewCache[map[string]*SomeLongTypeName, chan<- SomeLongEventTypeName, SomeOtherLongTypeName](argument1, argument2)
+	cache2 := NewCache[map[string]*SomeLongTypeName, chan<- SomeLongEventTypeName, SomeOtherLongTypeName, AndAnother]()
+	value := SumIntsOrFloats[string, int64](map[string]int64{"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5})
+	fmt.Println(cache, cache2, value)
+}
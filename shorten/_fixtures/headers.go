@@ -0,0 +1,42 @@
+package fixtures
+
+import "fmt"
+
+func testHeaders() {
+	if err := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, thirdArgument); err != nil {
+		return
+	}
+
+	if value, ok := someMapWithALongName[someKeyWithALongName]; ok && isSomethingTrue(value, argument) {
+		return
+	} else if err := client.DoSomethingElseVeryLong(ctx, firstArgument, secondArgument); err != nil {
+		return
+	}
+
+	for i := computeTheStartingIndex(firstArgument, secondArgument, thirdArgument, fourth); i < 10; i++ {
+		fmt.Println(i)
+	}
+
+	for i := 0; i < len(someSliceWithAVeryLongName) && i < len(anotherSliceWithAVeryLongName) && ok; i++ {
+		fmt.Println(i)
+	}
+
+	for _, item := range client.ListAllOfTheItems(ctx, firstArgument, secondArgument, thirdArgument) {
+		fmt.Println(item)
+	}
+
+	switch result := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, third); result.Kind {
+	case "first":
+		fmt.Println(result)
+	}
+
+	switch client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, thirdArgument, fourthArgument) {
+	case "first":
+		fmt.Println("first")
+	}
+
+	switch value := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, thirdArgument).(type) {
+	case string:
+		fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", value)
+	}
+}
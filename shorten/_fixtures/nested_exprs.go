@@ -0,0 +1,38 @@
+package fixtures
+
+import "fmt"
+
+func testTypeAssertions(value interface{}) {
+	result := buildTheResultFromTheValue(value, firstArgument, secondArgument, thirdArgument).(string)
+	handler := value.(interface {
+		Handle(firstArgument string, secondArgument int, thirdArgument bool, fourth int) (string, error)
+	})
+	fmt.Println(result, handler)
+}
+
+func testIndexAndSlice(values []string) {
+	first := valuesByKey[buildTheKeyFromTheArguments(firstArgument, secondArgument, thirdArgument, fourth)]
+	rest := values[computeTheStartIndex(firstArgument, secondArgument):computeTheEndIndex(thirdArgument)]
+	fmt.Println(first, rest)
+}
+
+func testStarAndParen() {
+	value := *buildAPointerToTheValue(firstArgument, secondArgument, thirdArgument, fourthArgument, fifth)
+	result := (*someStructPointer).DoSomethingWithTheArguments(firstArgument, secondArgument, thirdArgument)
+	fmt.Println(value, result)
+}
+
+func testSendAndIncDec(ch chan<- string) {
+	ch <- buildTheMessageToSend(firstArgument, secondArgument, thirdArgument, fourthArgument, fifthArgument)
+	countsByKey[buildTheKeyFromTheArguments(firstArgument, secondArgument, thirdArgument, fourthArgument)]++
+	getTheChannelForTheArguments(firstArgument, secondArgument, thirdArgument, fourthArgument) <- value
+	getTheChannel(firstArgument, secondArgument) <- buildTheMessageToSend(firstArgument, secondArgument)
+}
+
+func testLabeled() {
+outer:
+	for _, item := range client.ListAllOfTheItems(ctx, firstArgument, secondArgument, thirdArgument) {
+		fmt.Println(item)
+		break outer
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package shorten
 
 import (
 	"fmt"
@@ -52,8 +52,20 @@ func HasAnnotationRecursive(node dst.Node) bool {
 
 	switch n := node.(type) {
 	case *dst.FuncDecl:
-		if n.Type != nil && n.Type.Params != nil {
-			for _, item := range n.Type.Params.List {
+		if n.Type == nil {
+			return false
+		}
+
+		for _, fieldList := range []*dst.FieldList{
+			n.Type.TypeParams,
+			n.Type.Params,
+			n.Type.Results,
+		} {
+			if fieldList == nil {
+				continue
+			}
+
+			for _, item := range fieldList.List {
 				if HasAnnotationRecursive(item) {
 					return true
 				}
@@ -0,0 +1,63 @@
+package shorten
+
+import (
+	"errors"
+	"strings"
+)
+
+// Placeholder in the base formatter command that's replaced with the path of the file being
+// shortened
+const pathPlaceholder = "{{path}}"
+
+// splitCommand splits the provided command into its arguments using shell-style rules:
+// arguments are separated by any amount of whitespace, single quotes preserve everything
+// inside them, and double quotes preserve everything inside them except for backslash
+// escapes. No other shell features (variables, globs, etc.) are supported.
+func splitCommand(command string) ([]string, error) {
+	args := []string{}
+	current := &strings.Builder{}
+	inArg := false
+
+	var quote rune
+	escaped := false
+
+	for _, char := range command {
+		switch {
+		case escaped:
+			current.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				current.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, errors.New("unterminated escape at end of command")
+	} else if quote != 0 {
+		return nil, errors.New("unterminated quote in command")
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
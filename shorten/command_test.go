@@ -0,0 +1,55 @@
+package shorten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitCommand(t *testing.T) {
+	type testCase struct {
+		command  string
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			command:  "gofmt",
+			expected: []string{"gofmt"},
+		},
+		{
+			command:  "  goimports   -local  github.com/segmentio ",
+			expected: []string{"goimports", "-local", "github.com/segmentio"},
+		},
+		{
+			command: `my-formatter --name "two words" 'single \quoted' esc\ aped ""`,
+			expected: []string{
+				"my-formatter",
+				"--name",
+				"two words",
+				`single \quoted`,
+				"esc aped",
+				"",
+			},
+		},
+		{
+			command:  `sh -c "echo \"hi\""`,
+			expected: []string{"sh", "-c", `echo "hi"`},
+		},
+		{
+			command:  "",
+			expected: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		args, err := splitCommand(testCase.command)
+		assert.Nil(t, err, testCase.command)
+		assert.Equal(t, testCase.expected, args, testCase.command)
+	}
+
+	for _, command := range []string{`gofmt "unterminated`, `gofmt 'unterminated`, `gofmt \`} {
+		_, err := splitCommand(command)
+		assert.NotNil(t, err, command)
+	}
+}
@@ -0,0 +1,208 @@
+package shorten
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Exempts the next statement or declaration from shortening
+	ignoreDirective = "ignore"
+
+	// Disables and re-enables shortening for all of the lines in between
+	offDirective = "off"
+	onDirective  = "on"
+
+	// Overrides the max line length for the next statement or declaration
+	maxLenDirective = "max-len"
+)
+
+// Directive comments that control the shortener, e.g. //golines:ignore or
+// //golines:max-len=140. As with go directives, there's no space between the slashes and the
+// directive name. Anything after the name (and value) and a space is treated as an
+// explanation and ignored.
+var directiveLine = regexp.MustCompile(`^\s*//golines:([a-z-]+)(=(\S*))?(\s.*)?$`)
+
+// ParseDirective returns the name and value of the golines directive in the provided line,
+// e.g. "max-len" and "140" for "//golines:max-len=140". The value is empty for directives
+// that don't take one. If the line isn't a directive, both return values are empty.
+func ParseDirective(line string) (string, string) {
+	matches := directiveLine.FindStringSubmatch(line)
+	if matches == nil {
+		return "", ""
+	}
+	return matches[1], matches[3]
+}
+
+// HasIgnoreDirective determines whether the given AST node is preceded by a
+// //golines:ignore directive.
+func HasIgnoreDirective(node dst.Node) bool {
+	for _, decoration := range node.Decorations().Start.All() {
+		if name, _ := ParseDirective(decoration); name == ignoreDirective {
+			return true
+		}
+	}
+	return false
+}
+
+// directiveTracker keeps track of the //golines:off and //golines:on directives seen while
+// iterating over a list of sibling nodes (e.g., the statements in a block).
+type directiveTracker struct {
+	off bool
+}
+
+// skip updates the tracker with the directives attached to the provided node and returns
+// whether the node is exempt from shortening. Directives that come before the node apply to
+// it, while ones that come after it only apply to the nodes that follow.
+func (d *directiveTracker) skip(node dst.Node) bool {
+	d.update(node.Decorations().Start.All())
+	skip := d.off || HasIgnoreDirective(node)
+	d.update(node.Decorations().End.All())
+
+	return skip
+}
+
+// update updates the tracker state from the provided decorations.
+func (d *directiveTracker) update(decorations []string) {
+	for _, decoration := range decorations {
+		switch name, _ := ParseDirective(decoration); name {
+		case offDirective:
+			d.off = true
+		case onDirective:
+			d.off = false
+		}
+	}
+}
+
+// lineDirectives applies the golines directives in the provided lines. It returns whether
+// each line is exempt from shortening, because it's either in a //golines:off region or
+// part of a node with a //golines:ignore directive on it, and the max length for each line,
+// which is the provided default unless overridden via a //golines:max-len directive. Both
+// results have one entry per line.
+func lineDirectives(lines []string, defaultMaxLen int) ([]bool, []int) {
+	ignored := make([]bool, len(lines))
+	maxLens := make([]int, len(lines))
+	off := false
+	hasNodeDirectives := false
+
+	for l, line := range lines {
+		switch name, _ := ParseDirective(line); name {
+		case offDirective:
+			off = true
+		case onDirective:
+			off = false
+		case ignoreDirective, maxLenDirective:
+			hasNodeDirectives = true
+		}
+		ignored[l] = off
+		maxLens[l] = defaultMaxLen
+	}
+
+	if !hasNodeDirectives {
+		return ignored, maxLens
+	}
+
+	// Parse the source so that we can figure out the line ranges of the nodes that the
+	// directives apply to
+	fileSet := token.NewFileSet()
+	file, err := parser.ParseFile(
+		fileSet,
+		"",
+		strings.Join(lines, "\n"),
+		parser.ParseComments,
+	)
+	if err != nil {
+		// Just use the off regions; the parse error will be surfaced later on
+		return ignored, maxLens
+	}
+
+	// Comments are processed in order, so directives on nested nodes take precedence over
+	// the ones on their parents
+	for _, commentGroup := range file.Comments {
+		for _, comment := range commentGroup.List {
+			name, value := ParseDirective(comment.Text)
+			if name != ignoreDirective && name != maxLenDirective {
+				continue
+			}
+
+			var maxLen int
+			if name == maxLenDirective {
+				maxLen, err = strconv.Atoi(value)
+				if err != nil || maxLen <= 0 {
+					log.Debugf("ignoring invalid directive: %s", comment.Text)
+					continue
+				}
+			}
+
+			node := nextNode(file, comment.End())
+			if node == nil {
+				continue
+			}
+
+			start := fileSet.Position(node.Pos()).Line
+			end := fileSet.Position(node.End()).Line
+			for l := start; l <= end && l <= len(lines); l++ {
+				if name == ignoreDirective {
+					ignored[l-1] = true
+				} else {
+					maxLens[l-1] = maxLen
+				}
+			}
+		}
+	}
+
+	return ignored, maxLens
+}
+
+// nextNode returns the non-comment node that a directive ending at the provided position
+// applies to. This is the first child of the innermost node that contains the directive (e.g.
+// the next statement in the same block) that starts after it, or nil if there isn't one, e.g.
+// because the directive is the last thing in the block.
+func nextNode(file *ast.File, pos token.Pos) ast.Node {
+	var result ast.Node
+
+	// Innermost node that contains the directive, and the stack of nodes that are currently
+	// being inspected
+	var enclosing ast.Node
+	stack := []ast.Node{}
+
+	ast.Inspect(
+		file,
+		func(node ast.Node) bool {
+			switch node.(type) {
+			case nil:
+				stack = stack[:len(stack)-1]
+				return false
+			case *ast.CommentGroup, *ast.Comment:
+				return false
+			}
+
+			var parent ast.Node
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1]
+			}
+
+			if result != nil || node.End() <= pos {
+				return false
+			} else if node.Pos() > pos {
+				if parent == enclosing {
+					result = node
+				}
+				return false
+			}
+
+			enclosing = node
+			stack = append(stack, node)
+			return true
+		},
+	)
+
+	return result
+}
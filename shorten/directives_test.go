@@ -0,0 +1,81 @@
+package shorten
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirective(t *testing.T) {
+	testCases := []struct {
+		line          string
+		expectedName  string
+		expectedValue string
+	}{
+		{"//golines:ignore", "ignore", ""},
+		{"\t//golines:ignore hand-aligned table", "ignore", ""},
+		{"//golines:off", "off", ""},
+		{"  //golines:on", "on", ""},
+		{"//golines:max-len=140", "max-len", "140"},
+		{"//golines:max-len=140 long SQL queries", "max-len", "140"},
+		{"// golines:ignore", "", ""},
+		{"//go:generate", "", ""},
+		{"x := 5 //golines:ignore", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		name, value := ParseDirective(testCase.line)
+		assert.Equal(t, testCase.expectedName, name, testCase.line)
+		assert.Equal(t, testCase.expectedValue, value, testCase.line)
+	}
+}
+
+func TestLineDirectives(t *testing.T) {
+	lines := strings.Split(
+		`package main
+
+func main() {
+	//golines:ignore
+	fmt.Println(
+		"hello",
+	)
+	fmt.Println("not ignored")
+	//golines:off
+	fmt.Println("off")
+	//golines:on
+	fmt.Println("on")
+	//golines:max-len=140
+	fmt.Println("longer")
+}`,
+		"\n",
+	)
+	ignored, maxLens := lineDirectives(lines, 100)
+
+	assert.Equal(
+		t,
+		[]bool{
+			false,
+			false,
+			false,
+			false,
+			true,
+			true,
+			true,
+			false,
+			true,
+			true,
+			false,
+			false,
+			false,
+			false,
+			false,
+		},
+		ignored,
+	)
+	assert.Equal(
+		t,
+		[]int{100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 140, 100},
+		maxLens,
+	)
+}
@@ -0,0 +1,25 @@
+/*
+Package shorten contains the line-shortening engine behind the golines command.
+
+The main entry point is the Shortener, which is created from a ShortenerConfig and
+rewrites the contents of a single go file so that its lines fit within the configured
+maximum length:
+
+	shortener := shorten.NewShortener(
+		shorten.ShortenerConfig{
+			MaxLen:         100,
+			TabLen:         4,
+			ReformatTags:   true,
+			ChainSplitDots: true,
+		},
+	)
+	result, err := shortener.Shorten(contents)
+
+ShortenContext can be used instead of Shorten to be able to cancel the shortening via a
+context.
+
+The package also exposes the lower-level helpers used by the shortener, including the
+annotation functions (CreateAnnotation, ParseAnnotation, etc.), FormatStructTags,
+and PrettyDiff.
+*/
+package shorten // import "github.com/segmentio/golines/shorten"
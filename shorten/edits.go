@@ -0,0 +1,87 @@
+package shorten
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// TextEdit is a change to a file's contents that replaces the Length bytes starting at
+// Offset with NewText.
+type TextEdit struct {
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+	NewText string `json:"newText"`
+}
+
+// ShortenEdits is like Shorten, but returns the edits that need to be applied to the
+// provided contents instead of the full result. This allows editors to update just the
+// lines that changed rather than replacing the whole buffer.
+func (s *Shortener) ShortenEdits(contents []byte) ([]TextEdit, error) {
+	result, err := s.Shorten(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeEdits(contents, result), nil
+}
+
+// ComputeEdits returns a minimal list of line-based edits that transform the provided
+// original contents into the result. The edits are sorted by offset and don't overlap;
+// all offsets are relative to the original contents.
+func ComputeEdits(original []byte, result []byte) []TextEdit {
+	edits := []TextEdit{}
+
+	originalLines := splitLinesAfter(string(original))
+	resultLines := splitLinesAfter(string(result))
+
+	// Byte offset of the start of each original line, plus one for the end of the contents
+	offsets := make([]int, len(originalLines)+1)
+	for l, line := range originalLines {
+		offsets[l+1] = offsets[l] + len(line)
+	}
+
+	matcher := difflib.NewMatcherWithJunk(originalLines, resultLines, false, nil)
+
+	for _, opCode := range matcher.GetOpCodes() {
+		if opCode.Tag == 'e' {
+			continue
+		}
+
+		edits = append(
+			edits,
+			TextEdit{
+				Offset:  offsets[opCode.I1],
+				Length:  offsets[opCode.I2] - offsets[opCode.I1],
+				NewText: strings.Join(resultLines[opCode.J1:opCode.J2], ""),
+			},
+		)
+	}
+
+	return edits
+}
+
+// ApplyEdits applies the provided edits, which must be sorted by offset and not overlap, to
+// the contents and returns the result.
+func ApplyEdits(contents []byte, edits []TextEdit) []byte {
+	result := []byte{}
+	offset := 0
+
+	for _, edit := range edits {
+		result = append(result, contents[offset:edit.Offset]...)
+		result = append(result, edit.NewText...)
+		offset = edit.Offset + edit.Length
+	}
+
+	return append(result, contents[offset:]...)
+}
+
+// splitLinesAfter splits the provided contents into lines, keeping the newline at the end
+// of each one.
+func splitLinesAfter(contents string) []string {
+	lines := strings.SplitAfter(contents, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
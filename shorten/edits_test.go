@@ -0,0 +1,89 @@
+package shorten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeEdits(t *testing.T) {
+	type testCase struct {
+		original string
+		result   string
+		expected []TextEdit
+	}
+
+	testCases := []testCase{
+		{
+			original: "line1\nline2\n",
+			result:   "line1\nline2\n",
+			expected: []TextEdit{},
+		},
+		{
+			original: "line1\nline2\nline3\n",
+			result:   "line1\nline2a\nline2b\nline3\n",
+			expected: []TextEdit{
+				{Offset: 6, Length: 6, NewText: "line2a\nline2b\n"},
+			},
+		},
+		{
+			original: "line1\nline2\nline3\nline4\n",
+			result:   "line1a\nline2\nline3\n",
+			expected: []TextEdit{
+				{Offset: 0, Length: 6, NewText: "line1a\n"},
+				{Offset: 18, Length: 6, NewText: ""},
+			},
+		},
+		{
+			original: "line1",
+			result:   "line1\nline2",
+			expected: []TextEdit{
+				{Offset: 0, Length: 5, NewText: "line1\nline2"},
+			},
+		},
+		{
+			original: "",
+			result:   "package main\n",
+			expected: []TextEdit{
+				{Offset: 0, Length: 0, NewText: "package main\n"},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		edits := ComputeEdits([]byte(testCase.original), []byte(testCase.result))
+		assert.Equal(t, testCase.expected, edits)
+		assert.Equal(
+			t,
+			testCase.result,
+			string(ApplyEdits([]byte(testCase.original), edits)),
+		)
+	}
+}
+
+func TestShortenEdits(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+
+	contents := []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	edits, err := shortener.ShortenEdits(contents)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(edits))
+
+	result, err := shortener.Shorten(contents)
+	assert.Nil(t, err)
+	assert.Equal(t, string(result), string(ApplyEdits(contents, edits)))
+}
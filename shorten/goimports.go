@@ -0,0 +1,70 @@
+package shorten
+
+import (
+	"sync"
+
+	"golang.org/x/tools/imports"
+)
+
+// The goimports library only supports setting the local prefixes via a global variable, which
+// is read at the start of each call to imports.Process. To allow concurrent calls, the
+// variable is guarded by a lock that can be shared by any number of calls that use the same
+// prefixes; calls that need different prefixes wait until the current ones are done.
+var localPrefixLock = struct {
+	sync.Mutex
+	cond     *sync.Cond
+	prefixes string
+	active   int
+}{}
+
+func init() {
+	localPrefixLock.cond = sync.NewCond(&localPrefixLock.Mutex)
+}
+
+// runGoimports formats the provided contents with goimports as if they were in the file at
+// the provided path, adding and removing imports as needed. If filename is empty, imports are
+// resolved relative to the current directory.
+func runGoimports(filename string, contents []byte, localPrefixes string) ([]byte, error) {
+	acquireLocalPrefixes(localPrefixes)
+	defer releaseLocalPrefixes()
+
+	return imports.Process(
+		filename,
+		contents,
+		&imports.Options{
+			Comments:  true,
+			TabIndent: true,
+			TabWidth:  8,
+		},
+	)
+}
+
+// acquireLocalPrefixes sets the goimports local prefixes, waiting for any calls that use
+// different ones to finish first.
+func acquireLocalPrefixes(prefixes string) {
+	localPrefixLock.Lock()
+	defer localPrefixLock.Unlock()
+
+	for localPrefixLock.active > 0 && localPrefixLock.prefixes != prefixes {
+		localPrefixLock.cond.Wait()
+	}
+
+	if localPrefixLock.active == 0 {
+		// Other calls with the same prefixes might be reading the variable, so it's only
+		// written when there aren't any
+		imports.LocalPrefix = prefixes
+		localPrefixLock.prefixes = prefixes
+	}
+	localPrefixLock.active++
+}
+
+// releaseLocalPrefixes releases the local prefixes acquired via acquireLocalPrefixes.
+func releaseLocalPrefixes() {
+	localPrefixLock.Lock()
+	defer localPrefixLock.Unlock()
+
+	localPrefixLock.active--
+	if localPrefixLock.active == 0 {
+		localPrefixLock.cond.Broadcast()
+	}
+}
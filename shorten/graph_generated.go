@@ -1,6 +1,6 @@
 // Generated by generate/main.go. DO NOT EDIT.
 
-package main
+package shorten
 
 import (
 	"log"
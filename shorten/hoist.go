@@ -0,0 +1,133 @@
+package shorten
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// funcScope describes the function that some statements are in.
+type funcScope struct {
+	// Names of the receiver, params, and results of the function, which are in scope for all
+	// of the statements in its body
+	names map[string]bool
+
+	// Whether the function has named results, in which case they're referred to by bare
+	// return statements
+	namedResults bool
+}
+
+// canHoistIfInit determines whether the init statement of an if statement can be moved to a
+// separate statement before it without changing the meaning of the code. The if statement is
+// at the provided index in stmts, and scope describes the function that they're in, if any.
+//
+// Moving an init statement that declares variables extends their scope to the rest of the
+// enclosing block, so this is only done if none of the other statements in the block refer to
+// the same names, none of them are bare returns that could refer to named results that the
+// names would shadow, and the names don't shadow any of the function's params or results.
+// This is a conservative check; it also rejects some cases, e.g. ones where the names are only
+// used as struct fields, in which hoisting would be safe.
+func canHoistIfInit(
+	ifStmt *dst.IfStmt,
+	stmts []dst.Stmt,
+	index int,
+	scope *funcScope,
+) bool {
+	if ifStmt.Init == nil || len(ifStmt.Decs.Init) > 0 {
+		// Nothing to hoist, or there are comments after the init statement that would be
+		// lost
+		return false
+	}
+
+	names := map[string]bool{}
+	if assignStmt, ok := ifStmt.Init.(*dst.AssignStmt); ok && assignStmt.Tok == token.DEFINE {
+		for _, expr := range assignStmt.Lhs {
+			if ident, ok := expr.(*dst.Ident); ok && ident.Name != "_" {
+				names[ident.Name] = true
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return true
+	}
+
+	if scope != nil {
+		for name := range names {
+			if scope.names[name] {
+				return false
+			}
+		}
+	}
+
+	for s, stmt := range stmts {
+		if _, ok := stmt.(*dst.LabeledStmt); ok {
+			// A goto can't jump over a variable declaration to a label in its scope
+			return false
+		}
+
+		conflict := false
+		dst.Inspect(
+			stmt,
+			func(node dst.Node) bool {
+				switch n := node.(type) {
+				case *dst.FuncLit:
+					// Bare returns in function literals refer to the literal's results, so
+					// only the names in it need to be checked
+					if s != index && hasIdent(n, names) {
+						conflict = true
+					}
+					return false
+				case *dst.Ident:
+					if s != index && names[n.Name] {
+						conflict = true
+					}
+				case *dst.ReturnStmt:
+					if len(n.Results) == 0 && scope != nil && scope.namedResults {
+						conflict = true
+					}
+				}
+				return !conflict
+			},
+		)
+
+		if conflict {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasIdent determines whether the provided node refers to any of the provided names.
+func hasIdent(node dst.Node, names map[string]bool) bool {
+	found := false
+	dst.Inspect(
+		node,
+		func(n dst.Node) bool {
+			if ident, ok := n.(*dst.Ident); ok && names[ident.Name] {
+				found = true
+			}
+			return !found
+		},
+	)
+	return found
+}
+
+// hoistIfInit removes the init statement from an if statement and returns it so that it can be
+// added before the if statement, e.g. "if err := f(); err != nil {" becomes "err := f()"
+// followed by "if err != nil {". Any comments before the if statement are moved to the init
+// statement.
+func hoistIfInit(ifStmt *dst.IfStmt) dst.Stmt {
+	init := ifStmt.Init
+	ifStmt.Init = nil
+
+	init.Decorations().Before = ifStmt.Decs.Before
+	init.Decorations().Start = ifStmt.Decs.Start
+	init.Decorations().After = dst.NewLine
+
+	ifStmt.Decs.Before = dst.NewLine
+	ifStmt.Decs.Start = nil
+
+	return init
+}
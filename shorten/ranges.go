@@ -0,0 +1,259 @@
+package shorten
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// LineRange is an inclusive range of (1-based) line numbers in a file.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// ParseLineRange parses a line range in start:end format, e.g. "10:20".
+func ParseLineRange(value string) (LineRange, error) {
+	components := strings.Split(value, ":")
+	if len(components) != 2 {
+		return LineRange{}, fmt.Errorf("invalid line range %q; expected start:end", value)
+	}
+
+	start, err := strconv.Atoi(components[0])
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q: %+v", value, err)
+	}
+	end, err := strconv.Atoi(components[1])
+	if err != nil {
+		return LineRange{}, fmt.Errorf("invalid line range %q: %+v", value, err)
+	}
+
+	if start < 1 || end < start {
+		return LineRange{}, fmt.Errorf("invalid line range %q", value)
+	}
+
+	return LineRange{Start: start, End: end}, nil
+}
+
+// Contains determines whether the provided (1-based) line number is in the range.
+func (r LineRange) Contains(line int) bool {
+	return line >= r.Start && line <= r.End
+}
+
+// overlaps determines whether the range has any lines in common with the other one.
+func (r LineRange) overlaps(other LineRange) bool {
+	return r.Start <= other.End && r.End >= other.Start
+}
+
+// ByteRange is a range of byte offsets in a file, from Start (inclusive) to End
+// (exclusive).
+type ByteRange struct {
+	Start int
+	End   int
+}
+
+// ParseByteRange parses a range of byte offsets in #start-#end format, e.g. "#120-#450".
+func ParseByteRange(value string) (ByteRange, error) {
+	components := strings.Split(value, "-")
+	if len(components) != 2 ||
+		!strings.HasPrefix(components[0], "#") ||
+		!strings.HasPrefix(components[1], "#") {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q; expected #start-#end", value)
+	}
+
+	start, err := strconv.Atoi(components[0][1:])
+	if err != nil {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q: %+v", value, err)
+	}
+	end, err := strconv.Atoi(components[1][1:])
+	if err != nil {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q: %+v", value, err)
+	}
+
+	if start < 0 || end < start {
+		return ByteRange{}, fmt.Errorf("invalid byte range %q", value)
+	}
+
+	return ByteRange{Start: start, End: end}, nil
+}
+
+// LineRange returns the range of lines in the provided file contents that overlap the byte
+// range. Offsets past the end of the contents are treated as pointing to the end.
+func (r ByteRange) LineRange(contents []byte) LineRange {
+	start := min(r.Start, len(contents))
+	end := max(min(r.End, len(contents)), start)
+
+	startLine := bytes.Count(contents[:start], []byte("\n")) + 1
+	endLine := startLine
+	if end > start {
+		endLine += bytes.Count(contents[start:end-1], []byte("\n"))
+	}
+
+	return LineRange{Start: startLine, End: endLine}
+}
+
+// ShortenRange shortens the long lines in the provided range of the file contents, like
+// ShortenRanges, but returns the edits that need to be applied to the original contents
+// instead of the full result. This is useful for editors that reformat a selection. Edits
+// that don't overlap the range, e.g. ones for changes that the base formatter made
+// elsewhere in the file, are dropped.
+func (s *Shortener) ShortenRange(contents []byte, lineRange LineRange) ([]TextEdit, error) {
+	result, err := s.ShortenRanges(contents, []LineRange{lineRange})
+	if err != nil {
+		return nil, err
+	}
+
+	return rangeEdits(contents, result, lineRange), nil
+}
+
+// rangeEdits is like ComputeEdits, but only returns the edits that overlap the provided range
+// of lines in the original contents. Lines are matched up ignoring whitespace so that
+// formatting changes next to the range end up in separate edits that can be dropped.
+func rangeEdits(original []byte, result []byte, lineRange LineRange) []TextEdit {
+	edits := []TextEdit{}
+
+	originalLines := splitLinesAfter(string(original))
+	resultLines := splitLinesAfter(string(result))
+
+	// Byte offset of the start of each original line, plus one for the end of the contents
+	offsets := make([]int, len(originalLines)+1)
+	for l, line := range originalLines {
+		offsets[l+1] = offsets[l] + len(line)
+	}
+
+	addEdit := func(i1 int, i2 int, j1 int, j2 int) {
+		// Insertions go between lines i1 and i1+1, so they overlap the range if either one
+		// is in it
+		editLines := LineRange{Start: i1 + 1, End: i2}
+		if i1 == i2 {
+			editLines = LineRange{Start: i1, End: i1 + 1}
+		}
+		if !editLines.overlaps(lineRange) {
+			return
+		}
+
+		edits = append(
+			edits,
+			TextEdit{
+				Offset:  offsets[i1],
+				Length:  offsets[i2] - offsets[i1],
+				NewText: strings.Join(resultLines[j1:j2], ""),
+			},
+		)
+	}
+
+	matcher := difflib.NewMatcherWithJunk(
+		stripLines(originalLines),
+		stripLines(resultLines),
+		false,
+		nil,
+	)
+
+	for _, opCode := range matcher.GetOpCodes() {
+		if opCode.Tag != 'e' {
+			addEdit(opCode.I1, opCode.I2, opCode.J1, opCode.J2)
+			continue
+		}
+
+		// Lines that only differ in whitespace
+		for offset := 0; offset < opCode.I2-opCode.I1; offset++ {
+			i, j := opCode.I1+offset, opCode.J1+offset
+			if originalLines[i] != resultLines[j] {
+				addEdit(i, i+1, j, j+1)
+			}
+		}
+	}
+
+	return edits
+}
+
+// lineFilter keeps track of which lines in a file are eligible for shortening when only
+// some line ranges of the original file contents should be shortened. Since the line
+// numbers change as the file is formatted and shortened, lines are matched up with the
+// original ones via a diff.
+type lineFilter struct {
+	original []string
+	selected []bool
+}
+
+// newLineFilter creates a lineFilter for the provided original file contents and ranges.
+func newLineFilter(contents []byte, ranges []LineRange) *lineFilter {
+	original := trimLines(strings.Split(string(contents), "\n"))
+	selected := make([]bool, len(original))
+
+	for l := range original {
+		for _, lineRange := range ranges {
+			if lineRange.Contains(l + 1) {
+				selected[l] = true
+				break
+			}
+		}
+	}
+
+	return &lineFilter{original: original, selected: selected}
+}
+
+// matches returns whether each of the provided lines corresponds to a selected line in the
+// original file contents. Unchanged lines inherit the selection of the original lines
+// that they match, while new or changed lines are selected if any of the original lines
+// that they replace (or, for pure insertions, the ones next to them) are selected. If the
+// filter is nil, all lines are selected.
+func (f *lineFilter) matches(lines []string) []bool {
+	matched := make([]bool, len(lines))
+
+	if f == nil {
+		for l := range matched {
+			matched[l] = true
+		}
+		return matched
+	}
+
+	matcher := difflib.NewMatcherWithJunk(f.original, trimLines(lines), false, nil)
+
+	for _, opCode := range matcher.GetOpCodes() {
+		switch opCode.Tag {
+		case 'e':
+			for offset := 0; offset < opCode.J2-opCode.J1; offset++ {
+				matched[opCode.J1+offset] = f.selected[opCode.I1+offset]
+			}
+		case 'r', 'i':
+			selected := false
+			if opCode.Tag == 'r' {
+				for i := opCode.I1; i < opCode.I2; i++ {
+					selected = selected || f.selected[i]
+				}
+			} else {
+				selected = (opCode.I1 > 0 && f.selected[opCode.I1-1]) ||
+					(opCode.I1 < len(f.selected) && f.selected[opCode.I1])
+			}
+
+			for j := opCode.J1; j < opCode.J2; j++ {
+				matched[j] = selected
+			}
+		}
+	}
+
+	return matched
+}
+
+// stripLines returns a copy of the provided lines with all whitespace removed.
+func stripLines(lines []string) []string {
+	stripped := make([]string, len(lines))
+	for l, line := range lines {
+		stripped[l] = strings.Join(strings.Fields(line), "")
+	}
+	return stripped
+}
+
+// trimLines returns a copy of the provided lines with leading and trailing whitespace
+// removed so that indentation changes don't affect the matching.
+func trimLines(lines []string) []string {
+	trimmed := make([]string, len(lines))
+	for l, line := range lines {
+		trimmed[l] = strings.TrimSpace(line)
+	}
+	return trimmed
+}
@@ -0,0 +1,184 @@
+package shorten
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLineRange(t *testing.T) {
+	lineRange, err := ParseLineRange("10:20")
+	assert.Nil(t, err)
+	assert.Equal(t, LineRange{Start: 10, End: 20}, lineRange)
+	assert.True(t, lineRange.Contains(10))
+	assert.True(t, lineRange.Contains(20))
+	assert.False(t, lineRange.Contains(21))
+
+	for _, value := range []string{"10", "10:x", "20:10", "0:5", "1:2:3"} {
+		_, err := ParseLineRange(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestParseByteRange(t *testing.T) {
+	byteRange, err := ParseByteRange("#10-#20")
+	assert.Nil(t, err)
+	assert.Equal(t, ByteRange{Start: 10, End: 20}, byteRange)
+
+	for _, value := range []string{"10-20", "#10", "#10-20", "#20-#10", "#x-#20"} {
+		_, err := ParseByteRange(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestByteRangeLineRange(t *testing.T) {
+	contents := []byte("line1\nline2\nline3\n")
+
+	assert.Equal(t, LineRange{Start: 1, End: 1}, ByteRange{Start: 0, End: 6}.LineRange(contents))
+	assert.Equal(t, LineRange{Start: 1, End: 2}, ByteRange{Start: 2, End: 7}.LineRange(contents))
+	assert.Equal(t, LineRange{Start: 2, End: 2}, ByteRange{Start: 8, End: 8}.LineRange(contents))
+	assert.Equal(t, LineRange{Start: 3, End: 3}, ByteRange{Start: 12, End: 100}.LineRange(contents))
+}
+
+func TestShortenRange(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+
+	contents := []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	edits, err := shortener.ShortenRange(contents, LineRange{Start: 6, End: 6})
+	assert.Nil(t, err)
+
+	start := strings.Index(string(contents), "\tfmt.Printf")
+	assert.Equal(
+		t,
+		[]TextEdit{
+			{
+				Offset: start,
+				Length: strings.Index(string(contents[start:]), "\n") + 1,
+				NewText: `	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+`,
+			},
+		},
+		edits,
+	)
+
+	// Lines that don't need to be shortened don't generate any edits
+	edits, err = shortener.ShortenRange(contents, LineRange{Start: 1, End: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []TextEdit{}, edits)
+}
+
+func TestShortenRangeUnformatted(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+
+	contents := []byte(`package main
+
+func main() {
+	x:=1
+	y:=2
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	// The base formatter changes outside of the range aren't included
+	edits, err := shortener.ShortenRange(contents, LineRange{Start: 6, End: 6})
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(edits)) {
+		assert.Equal(t, strings.Index(string(contents), "\tfmt.Printf"), edits[0].Offset)
+		assert.NotContains(t, edits[0].NewText, "x := 1")
+	}
+
+	// Base formatter changes inside of the range are
+	edits, err = shortener.ShortenRange(contents, LineRange{Start: 5, End: 5})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		[]TextEdit{
+			{
+				Offset:  strings.Index(string(contents), "\ty:=2"),
+				Length:  len("\ty:=2\n"),
+				NewText: "\ty := 2\n",
+			},
+		},
+		edits,
+	)
+}
+
+func TestShortenRanges(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			ReformatTags:     true,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+
+	contents := []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	result, err := shortener.ShortenRanges(contents, []LineRange{{Start: 7, End: 7}})
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+}
+`,
+		string(result),
+	)
+
+	// Nothing is shortened if there are no ranges
+	result, err = shortener.ShortenRanges(contents, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, string(contents), string(result))
+}
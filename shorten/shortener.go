@@ -0,0 +1,1168 @@
+package shorten
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"os/exec"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	log "github.com/sirupsen/logrus"
+	gofumpt "mvdan.cc/gofumpt/format"
+)
+
+var (
+	// Strings to look for to identify generated files
+	generatedTerms = []string{
+		"do not edit",
+		"generated by",
+		"automatically regenerated",
+	}
+	// Go directives (should be ignored)
+	goDirectiveLine = regexp.MustCompile(`\s*//\s*go:.*`)
+)
+
+// The maximum number of shortening "rounds" that we'll allow. The shortening
+// process should converge quickly, but we have this here as a safety mechanism to
+// prevent loops that prevent termination.
+const maxRounds = 20
+
+// ShortenerConfig stores the configuration options exposed by a Shortener instance.
+type ShortenerConfig struct {
+	MaxLen          int    // Max target width for each line
+	TabLen          int    // Width of a tab character
+	KeepAnnotations bool   // Whether to keep annotations in final result (for debugging only)
+	ShortenComments bool   // Whether to shorten comments
+	ReformatTags    bool   // Whether to reformat struct tags in addition to shortening long lines
+	IgnoreGenerated bool   // Whether to ignore generated files
+	DotFile         string // Path to write dot-formatted output to (for debugging only)
+	ChainSplitDots  bool   // Whether to split chain methods by putting dots at ends of lines
+	HoistIfInit     bool   // Whether to move the init statements of long if statements before them
+	SplitStrings    bool   // Whether to split long string literals into concatenations
+
+	// Formatter that will be run before and after main shortening process. If empty,
+	// defaults to goimports. The goimports, gofmt, and gofumpt formatters are run
+	// in-process (unless arguments are provided for them); anything else is run as a
+	// subprocess. The command is split into arguments using shell-style quoting rules, and
+	// any {{path}} placeholders in it are replaced with the path of the file being
+	// shortened (see WithFilename).
+	BaseFormatterCmd string
+
+	// Maximum amount of time that the base formatter subprocess can run for before it's
+	// killed; zero means no limit
+	BaseFormatterTimeout time.Duration
+
+	// Comma-separated import path prefixes that goimports puts in a separate group after
+	// the third-party imports, as with goimports -local
+	LocalPrefixes string
+
+	// Options for the in-process gofumpt formatter; see the docs for gofumpt's
+	// format.Options for details
+	GofumptLangVersion string // Go version that the code is written in, e.g. "go1.22"
+	GofumptModulePath  string // Path of the module that contains the code
+	GofumptExtraRules  bool   // Whether to enable gofumpt's extra formatting rules
+}
+
+// Shortener shortens a single go file according to a small set of user style
+// preferences.
+type Shortener struct {
+	config ShortenerConfig
+
+	// Some extra params around the base formatter generated from the BaseFormatterCmd
+	// argument in the config.
+	baseFormatter     string
+	baseFormatterArgs []string
+
+	// Error from parsing BaseFormatterCmd, which is returned when the formatter is run
+	baseFormatterErr error
+
+	// Path of the file being shortened, if known; see WithFilename
+	filename string
+}
+
+// NewShortener creates a new shortener instance from the provided config.
+func NewShortener(config ShortenerConfig) *Shortener {
+	s := &Shortener{
+		config:            config,
+		baseFormatter:     "goimports",
+		baseFormatterArgs: []string{},
+	}
+
+	if strings.TrimSpace(config.BaseFormatterCmd) != "" {
+		formatterComponents, err := splitCommand(config.BaseFormatterCmd)
+		if err != nil {
+			s.baseFormatterErr = fmt.Errorf(
+				"invalid base formatter command %q: %+v",
+				config.BaseFormatterCmd,
+				err,
+			)
+		} else {
+			s.baseFormatter = formatterComponents[0]
+			s.baseFormatterArgs = formatterComponents[1:]
+		}
+	}
+
+	return s
+}
+
+// WithFilename returns a copy of the shortener that formats contents as if they were in the
+// file at the provided path. This allows goimports to resolve missing imports relative to
+// the file's module; without it, the current directory is used.
+func (s *Shortener) WithFilename(filename string) *Shortener {
+	fileShortener := *s
+	fileShortener.filename = filename
+	return &fileShortener
+}
+
+// Result stores the output of ShortenDetailed.
+type Result struct {
+	Contents []byte // Shortened file contents
+	Rounds   int    // Number of shortening rounds that were run
+}
+
+// LongLine is a line that's longer than the target maximum length.
+type LongLine struct {
+	Line   int `json:"line"`   // 1-based line number
+	Column int `json:"column"` // 1-based column of the first character past the max length
+	Length int `json:"length"` // Width of the line after tab expansion
+	MaxLen int `json:"maxLen"` // Maximum length that applies to the line
+}
+
+// Shorten shortens the provided golang file content bytes.
+func (s *Shortener) Shorten(contents []byte) ([]byte, error) {
+	return s.ShortenContext(context.Background(), contents)
+}
+
+// ShortenContext is like Shorten, but stops early if the provided context is canceled. The
+// context is checked between shortening rounds, and the base formatter subprocess (if any)
+// is killed when the context is done.
+func (s *Shortener) ShortenContext(ctx context.Context, contents []byte) ([]byte, error) {
+	result, err := s.shorten(ctx, contents, nil)
+	return result.Contents, err
+}
+
+// ShortenRanges is like Shorten, but only shortens the long lines that fall within the
+// provided ranges of the original file contents; all other lines are left as-is, aside
+// from the changes made by the base formatter. Struct tags aren't reformatted in this mode
+// since that would touch lines outside of the ranges.
+func (s *Shortener) ShortenRanges(contents []byte, ranges []LineRange) ([]byte, error) {
+	result, err := s.shortenRanges(context.Background(), contents, ranges)
+	return result.Contents, err
+}
+
+// ShortenDetailed is like Shorten, but also returns some details about the shortening
+// process. If ranges is non-nil, only the long lines within them are shortened, as with
+// ShortenRanges.
+func (s *Shortener) ShortenDetailed(contents []byte, ranges []LineRange) (Result, error) {
+	if ranges != nil {
+		return s.shortenRanges(context.Background(), contents, ranges)
+	}
+
+	return s.shorten(context.Background(), contents, nil)
+}
+
+// LongLines returns all of the lines in the provided file contents that are longer than the
+// target maximum length, taking max-len directives into account. Lines that are exempted
+// from shortening via golines directives are not included.
+func (s *Shortener) LongLines(contents []byte) []LongLine {
+	longLines := []LongLine{}
+	lines := strings.Split(string(contents), "\n")
+	ignored, maxLens := lineDirectives(lines, s.config.MaxLen)
+
+	for l, line := range lines {
+		length := s.lineLen(line)
+
+		if !ignored[l] && length > maxLens[l] {
+			longLines = append(
+				longLines,
+				LongLine{
+					Line:   l + 1,
+					Column: s.overflowColumn(line, maxLens[l]),
+					Length: length,
+					MaxLen: maxLens[l],
+				},
+			)
+		}
+	}
+
+	return longLines
+}
+
+// shortenRanges shortens the long lines within the provided ranges of the original file
+// contents. Struct tag reformatting is disabled since it could affect other lines.
+func (s *Shortener) shortenRanges(
+	ctx context.Context,
+	contents []byte,
+	ranges []LineRange,
+) (Result, error) {
+	rangesShortener := *s
+	rangesShortener.config.ReformatTags = false
+
+	return rangesShortener.shorten(ctx, contents, newLineFilter(contents, ranges))
+}
+
+// shorten implements Shorten, ShortenRanges, and ShortenDetailed. If filter is non-nil, only
+// the lines that it matches are shortened. If the context is canceled, its error is returned.
+func (s *Shortener) shorten(
+	ctx context.Context,
+	contents []byte,
+	filter *lineFilter,
+) (Result, error) {
+	if s.config.IgnoreGenerated && IsGenerated(contents) {
+		return Result{Contents: contents}, nil
+	}
+
+	round := 0
+	var err error
+
+	// Do initial, non-line-length-aware formatting
+	contents, err = s.formatSrc(ctx, contents)
+	if err != nil {
+		return Result{}, fmt.Errorf("error formatting source: %w", err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+
+		log.Debugf("starting round %d", round)
+
+		// Annotate all long lines
+		lines := strings.Split(string(contents), "\n")
+		annotatedLines, linesToShorten := s.annotateLongLines(lines, filter.matches(lines))
+		var stop bool
+
+		if linesToShorten == 0 {
+			if round == 0 {
+				if !s.config.ReformatTags {
+					stop = true
+				} else if !HasMultiKeyTags(lines) {
+					stop = true
+				}
+			} else {
+				stop = true
+			}
+		}
+
+		if stop {
+			log.Debug("nothing more to shorten or reformat, stopping")
+			break
+		}
+
+		contents = []byte(strings.Join(annotatedLines, "\n"))
+
+		// Generate AST
+		result, err := decorator.Parse(contents)
+		if err != nil {
+			return Result{}, err
+		}
+
+		if s.config.DotFile != "" {
+			dotFile, err := os.Create(s.config.DotFile)
+			if err != nil {
+				return Result{}, err
+			}
+			defer dotFile.Close()
+
+			log.Debugf("writing dot file output to %s", s.config.DotFile)
+			err = CreateDot(result, dotFile)
+			if err != nil {
+				return Result{}, err
+			}
+		}
+
+		// Shorten the file starting at the top-level declarations
+		tracker := &directiveTracker{}
+		for _, decl := range result.Decls {
+			if !tracker.skip(decl) {
+				s.formatNode(decl)
+			}
+		}
+
+		// Materialize output
+		output := bytes.NewBuffer([]byte{})
+		err = decorator.Fprint(output, result)
+		if err != nil {
+			return Result{}, fmt.Errorf("error parsing source: %+v", err)
+		}
+		contents = output.Bytes()
+
+		round++
+
+		if round > maxRounds {
+			log.Debugf("hit max rounds, stopping")
+			break
+		}
+	}
+
+	if !s.config.KeepAnnotations {
+		contents = s.removeAnnotations(contents)
+	}
+	if s.config.SplitStrings {
+		contents, err = s.splitStringsFunc(contents, filter)
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	if s.config.ShortenComments {
+		contents = s.shortenCommentsFunc(contents, filter)
+	}
+
+	// Do final round of non-line-length-aware formatting after we've fixed up the comments
+	contents, err = s.formatSrc(ctx, contents)
+	if err != nil {
+		return Result{}, fmt.Errorf("error formatting source: %w", err)
+	}
+
+	return Result{Contents: contents, Rounds: round}, nil
+}
+
+// formatSrc formats the provided source bytes using the configured "base" formatter (typically
+// goimports or gofmt). The goimports, gofmt, and gofumpt formatters are run in-process unless
+// arguments were provided for them.
+func (s *Shortener) formatSrc(ctx context.Context, contents []byte) ([]byte, error) {
+	if s.baseFormatterErr != nil {
+		return nil, s.baseFormatterErr
+	} else if s.baseFormatter == "goimports" && len(s.baseFormatterArgs) == 0 {
+		return runGoimports(s.filename, contents, s.config.LocalPrefixes)
+	} else if s.baseFormatter == "gofmt" && len(s.baseFormatterArgs) == 0 {
+		return format.Source(contents)
+	} else if s.baseFormatter == "gofumpt" && len(s.baseFormatterArgs) == 0 {
+		return gofumpt.Source(
+			contents,
+			gofumpt.Options{
+				LangVersion: s.config.GofumptLangVersion,
+				ModulePath:  s.config.GofumptModulePath,
+				ExtraRules:  s.config.GofumptExtraRules,
+			},
+		)
+	}
+
+	return s.runBaseFormatterCmd(ctx, contents)
+}
+
+// runBaseFormatterCmd formats the provided source bytes by running the base formatter as a
+// subprocess, passing the source via stdin. Any {{path}} placeholders in the arguments are
+// replaced with the path of the file being shortened (or an empty string if unknown). The
+// subprocess is killed if the provided context is done or the configured timeout passes.
+func (s *Shortener) runBaseFormatterCmd(
+	parentCtx context.Context,
+	contents []byte,
+) ([]byte, error) {
+	ctx := parentCtx
+	if s.config.BaseFormatterTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parentCtx, s.config.BaseFormatterTimeout)
+		defer cancel()
+	}
+
+	args := make([]string, len(s.baseFormatterArgs))
+	for a, arg := range s.baseFormatterArgs {
+		args[a] = strings.ReplaceAll(arg, pathPlaceholder, s.filename)
+	}
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, s.baseFormatter, args...)
+	cmd.Stdin = bytes.NewReader(contents)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if parentCtx.Err() != nil {
+		return nil, parentCtx.Err()
+	} else if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf(
+			"base formatter %q timed out after %s",
+			s.config.BaseFormatterCmd,
+			s.config.BaseFormatterTimeout,
+		)
+	} else if err != nil {
+		return nil, fmt.Errorf(
+			"error running base formatter %q: %+v: %s",
+			s.config.BaseFormatterCmd,
+			err,
+			strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// annotateLongLines adds specially-formatted comments to all eligible lines that are longer than
+// the configured target length. If a line already has one of these comments from a previous
+// shortening round, then the comment contents are updated. Lines that are exempted via golines
+// directives or that aren't selected are not annotated, and lines covered by a max-len directive
+// use its length instead.
+func (s *Shortener) annotateLongLines(lines []string, selected []bool) ([]string, int) {
+	annotatedLines := []string{}
+	linesToShorten := 0
+	prevLen := -1
+	ignored, maxLens := lineDirectives(lines, s.config.MaxLen)
+
+	for l, line := range lines {
+		length := s.lineLen(line)
+
+		if prevLen > -1 {
+			if length <= maxLens[l] {
+				// Shortening successful, remove previous annotation
+				annotatedLines = annotatedLines[:len(annotatedLines)-1]
+			} else if length < prevLen {
+				// Replace annotation with new length
+				annotatedLines[len(annotatedLines)-1] = CreateAnnotation(length)
+				linesToShorten++
+			}
+		} else if !s.isComment(line) && selected[l] && !ignored[l] && length > maxLens[l] {
+			annotatedLines = append(
+				annotatedLines,
+				CreateAnnotation(length),
+			)
+			linesToShorten++
+		}
+
+		annotatedLines = append(annotatedLines, line)
+		prevLen = ParseAnnotation(line)
+	}
+
+	return annotatedLines, linesToShorten
+}
+
+// removeAnnotations removes all comments that were added by the annotateLongLines
+// function above.
+func (s *Shortener) removeAnnotations(contents []byte) []byte {
+	cleanedLines := []string{}
+	lines := strings.Split(string(contents), "\n")
+
+	for _, line := range lines {
+		if !IsAnnotation(line) {
+			cleanedLines = append(cleanedLines, line)
+		}
+	}
+
+	return []byte(strings.Join(cleanedLines, "\n"))
+}
+
+// shortenCommentsFunc attempts to shorten long comments in the provided source. As noted
+// in the repo README, this functionality has some quirks and is disabled by default. If filter
+// is non-nil, only the comment lines that it matches are shortened.
+func (s *Shortener) shortenCommentsFunc(contents []byte, filter *lineFilter) []byte {
+	cleanedLines := []string{}
+	words := []string{} // all words in a contiguous sequence of long comments
+	prefix := ""
+	lines := strings.Split(string(contents), "\n")
+	ignored, _ := lineDirectives(lines, s.config.MaxLen)
+	selected := filter.matches(lines)
+	for l, line := range lines {
+		if s.isComment(line) && !IsAnnotation(line) &&
+			!s.isGoDirective(line) && selected[l] && !ignored[l] &&
+			s.lineLen(line) > s.config.MaxLen {
+			start := strings.Index(line, "//")
+			prefix = line[0:(start + 2)]
+			trimmedLine := strings.Trim(line[(start+2):], " ")
+			currLineWords := strings.Split(trimmedLine, " ")
+			words = append(words, currLineWords...)
+		} else {
+			// Reflow the accumulated `words` before appending the unprocessed `line`.
+			currLineLen := 0
+			currLineWords := []string{}
+			maxCommentLen := s.config.MaxLen - s.lineLen(prefix)
+			for _, word := range words {
+				if currLineLen > 0 && currLineLen+1+len(word) > maxCommentLen {
+					cleanedLines = append(
+						cleanedLines,
+						fmt.Sprintf(
+							"%s %s",
+							prefix,
+							strings.Join(currLineWords, " "),
+						),
+					)
+					currLineWords = []string{}
+					currLineLen = 0
+				}
+				currLineWords = append(currLineWords, word)
+				currLineLen += 1 + len(word)
+			}
+			if currLineLen > 0 {
+				cleanedLines = append(
+					cleanedLines,
+					fmt.Sprintf(
+						"%s %s",
+						prefix,
+						strings.Join(currLineWords, " "),
+					),
+				)
+			}
+			words = []string{}
+
+			cleanedLines = append(cleanedLines, line)
+		}
+	}
+	return []byte(strings.Join(cleanedLines, "\n"))
+}
+
+// lineLen gets the width of the provided line after tab expansion.
+func (s *Shortener) lineLen(line string) int {
+	length := 0
+
+	for _, char := range line {
+		if char == '\t' {
+			length += s.config.TabLen
+		} else {
+			length++
+		}
+	}
+
+	return length
+}
+
+// overflowColumn returns the 1-based column, in characters, of the first character in the
+// provided line that extends past the given max length.
+func (s *Shortener) overflowColumn(line string, maxLen int) int {
+	length := 0
+	column := 1
+
+	for _, char := range line {
+		if char == '\t' {
+			length += s.config.TabLen
+		} else {
+			length++
+		}
+
+		if length > maxLen {
+			break
+		}
+		column++
+	}
+
+	return column
+}
+
+// isComment determines whether the provided line is a non-block comment.
+func (s *Shortener) isComment(line string) bool {
+	return strings.HasPrefix(strings.Trim(line, " \t"), "//")
+}
+
+// isGoDirective determines whether the provided line is a go directive, e.g. for go generate.
+func (s *Shortener) isGoDirective(line string) bool {
+	return goDirectiveLine.MatchString(line)
+}
+
+// formatNode formats the provided AST node. The appropriate helper function is called
+// based on whether the node is a declaration, expression, statement, or spec.
+func (s *Shortener) formatNode(node dst.Node) {
+	switch n := node.(type) {
+	case dst.Decl:
+		log.Debugf("processing declaration: %+v", n)
+		s.formatDecl(n)
+	case dst.Expr:
+		log.Debugf("processing expression: %+v", n)
+		s.formatExpr(n, false, false)
+	case dst.Stmt:
+		log.Debugf("processing statement: %+v", n)
+		s.formatStmt(n, false, nil)
+	case dst.Spec:
+		log.Debugf("processing spec: %+v", n)
+		s.formatSpec(n, false)
+	default:
+		log.Debugf(
+			"got a node type that can't be shortened: %+v",
+			reflect.TypeOf(n),
+		)
+	}
+}
+
+// formatDecl formats an AST declaration node. These include function declarations,
+// imports, and constants.
+func (s *Shortener) formatDecl(decl dst.Decl) {
+	switch d := decl.(type) {
+	case *dst.FuncDecl:
+		if d.Type != nil {
+			s.formatFuncSignature(d)
+		}
+		s.formatFuncBody(d.Recv, d.Type, d.Body)
+	case *dst.GenDecl:
+		tracker := &directiveTracker{}
+		for _, spec := range d.Specs {
+			if !tracker.skip(spec) {
+				s.formatSpec(spec, HasAnnotation(decl))
+			}
+		}
+	default:
+		log.Debugf(
+			"got a declaration type that can't be shortened: %+v",
+			reflect.TypeOf(d),
+		)
+	}
+}
+
+// formatFuncSignature formats the signature of a function declaration. The params are split
+// first; the results are only split if the params are already split (or there aren't any)
+// and the line with the results is still too long, and the type params are only split if the
+// line with the function name is still too long after that.
+func (s *Shortener) formatFuncSignature(decl *dst.FuncDecl) {
+	if s.formatTypeParamConstraints(decl.Type.TypeParams) || !HasAnnotationRecursive(decl) {
+		return
+	}
+
+	switch {
+	case s.formatFieldList(decl.Type.Params):
+	case resultsAnnotated(decl) && s.formatFieldList(decl.Type.Results):
+	case HasAnnotation(decl):
+		s.formatFieldList(decl.Type.TypeParams)
+	}
+}
+
+// formatTypeParamConstraints splits the unions in the constraints of type params that are on
+// their own lines, but are still too long. It returns true if any of the type params had
+// an annotation.
+func (s *Shortener) formatTypeParamConstraints(typeParams *dst.FieldList) bool {
+	if typeParams == nil {
+		return false
+	}
+
+	annotated := false
+
+	for _, field := range typeParams.List {
+		if HasAnnotation(field) {
+			annotated = true
+			s.formatUnion(field.Type)
+		}
+	}
+
+	return annotated
+}
+
+// formatUnion puts each term of a union in a type constraint, e.g. "~int | ~int64 | ~float64",
+// on its own line.
+func (s *Shortener) formatUnion(expr dst.Expr) {
+	for {
+		binaryExpr, ok := expr.(*dst.BinaryExpr)
+		if !ok || binaryExpr.Op != token.OR {
+			return
+		}
+
+		binaryExpr.Y.Decorations().Before = dst.NewLine
+		expr = binaryExpr.X
+	}
+}
+
+// formatFieldList formats a field list in a function declaration, putting each field on its
+// own line. It returns false if the list was already formatted this way (or is empty), in
+// which case nothing was changed.
+func (s *Shortener) formatFieldList(fieldList *dst.FieldList) bool {
+	if fieldList == nil {
+		return false
+	}
+
+	changed := false
+
+	for f, field := range fieldList.List {
+		decorations := field.Decorations()
+		if f == 0 && decorations.Before != dst.NewLine || decorations.After != dst.NewLine {
+			changed = true
+		}
+
+		if f == 0 {
+			decorations.Before = dst.NewLine
+		} else {
+			decorations.Before = dst.None
+		}
+
+		decorations.After = dst.NewLine
+	}
+
+	return changed
+}
+
+// resultsAnnotated determines whether the line with the results of a function declaration
+// is too long. Depending on how the params are laid out, the annotation for this line is
+// either on the declaration itself or at the end of the last param. Results without
+// parentheses, i.e. a single unnamed type, can't be split and are ignored.
+func resultsAnnotated(decl *dst.FuncDecl) bool {
+	results := decl.Type.Results
+	if results == nil || !results.Opening || len(results.List) == 0 {
+		return false
+	}
+
+	for _, field := range results.List {
+		if HasAnnotationRecursive(field) {
+			return true
+		}
+	}
+
+	params := decl.Type.Params
+	if params == nil || len(params.List) == 0 {
+		return HasAnnotation(decl)
+	}
+	return HasTailAnnotation(params.List[len(params.List)-1])
+}
+
+// formatStmt formats an AST statement node. Among other examples, these include assignments,
+// case clauses, for statements, if statements, and select statements. If force is true, then
+// the statement is shortened even if it doesn't have an annotation; this is used for the
+// statements in the headers of if, for, and switch statements. The scope describes the
+// function that the statement is in, if any.
+func (s *Shortener) formatStmt(stmt dst.Stmt, force bool, scope *funcScope) {
+	// Explicitly check for nil statements
+	if stmt == nil {
+		return
+	}
+	stmtType := reflect.TypeOf(stmt)
+	if reflect.ValueOf(stmt) == reflect.Zero(stmtType) {
+		return
+	}
+
+	shouldShorten := force || HasAnnotation(stmt)
+
+	switch st := stmt.(type) {
+	case *dst.AssignStmt:
+		for _, expr := range st.Rhs {
+			s.formatExpr(expr, shouldShorten, false)
+		}
+	case *dst.BlockStmt:
+		st.List = s.formatStmtList(st.List, scope)
+	case *dst.CaseClause:
+		if shouldShorten {
+			for _, arg := range st.List {
+				arg.Decorations().After = dst.NewLine
+				s.formatExpr(arg, false, false)
+			}
+		}
+
+		st.Body = s.formatStmtList(st.Body, scope)
+	case *dst.CommClause:
+		st.Body = s.formatStmtList(st.Body, scope)
+	case *dst.DeclStmt:
+		s.formatDecl(st.Decl)
+	case *dst.DeferStmt:
+		s.formatExpr(st.Call, shouldShorten, false)
+	case *dst.ExprStmt:
+		s.formatExpr(st.X, shouldShorten, false)
+	case *dst.ForStmt:
+		// Split the init statement if possible, otherwise the condition, otherwise the post
+		// statement
+		switch {
+		case st.Init != nil && isSplittable(st.Init):
+			s.formatStmt(st.Init, shouldShorten, scope)
+		case st.Cond != nil:
+			s.formatExpr(st.Cond, shouldShorten, false)
+		default:
+			s.formatStmt(st.Post, shouldShorten, scope)
+		}
+		s.formatStmt(st.Body, false, scope)
+	case *dst.GoStmt:
+		s.formatExpr(st.Call, shouldShorten, false)
+	case *dst.IfStmt:
+		// The init statement, e.g. "err := f()" in "if err := f(); err != nil", usually
+		// contains the call that makes the line long, so split that first if possible
+		if st.Init != nil && isSplittable(st.Init) {
+			s.formatStmt(st.Init, shouldShorten, scope)
+			s.formatExpr(st.Cond, false, false)
+		} else {
+			s.formatExpr(st.Cond, shouldShorten, false)
+		}
+		s.formatStmt(st.Body, false, scope)
+
+		// The annotation for the "} else if ... {" line of a long else if statement is at the
+		// end of the last statement in the body
+		elseAnnotated := len(st.Body.List) > 0 &&
+			HasTailAnnotation(st.Body.List[len(st.Body.List)-1])
+		s.formatStmt(st.Else, elseAnnotated, scope)
+	case *dst.IncDecStmt:
+		s.formatExpr(st.X, shouldShorten, false)
+	case *dst.LabeledStmt:
+		s.formatStmt(st.Stmt, shouldShorten, scope)
+	case *dst.RangeStmt:
+		s.formatExpr(st.X, shouldShorten, false)
+		s.formatStmt(st.Body, false, scope)
+	case *dst.ReturnStmt:
+		for _, expr := range st.Results {
+			s.formatExpr(expr, shouldShorten, false)
+		}
+	case *dst.SelectStmt:
+		s.formatStmt(st.Body, false, scope)
+	case *dst.SendStmt:
+		s.formatOperands([]dst.Expr{st.Chan, st.Value}, shouldShorten, false)
+	case *dst.SwitchStmt:
+		if st.Init != nil && (st.Tag == nil || isSplittable(st.Init)) {
+			s.formatStmt(st.Init, shouldShorten, scope)
+		} else if st.Tag != nil {
+			s.formatExpr(st.Tag, shouldShorten, false)
+		}
+		s.formatStmt(st.Body, false, scope)
+	case *dst.TypeSwitchStmt:
+		if st.Init != nil && isSplittable(st.Init) {
+			s.formatStmt(st.Init, shouldShorten, scope)
+		} else {
+			s.formatStmt(st.Assign, shouldShorten, scope)
+		}
+		s.formatStmt(st.Body, false, scope)
+	default:
+		if shouldShorten {
+			log.Debugf(
+				"got a statement type that can't be shortened: %+v",
+				reflect.TypeOf(st),
+			)
+		}
+	}
+}
+
+// formatStmtList formats a list of sibling statements, e.g. the ones in a block, skipping any
+// that are exempted via golines directives. It returns the formatted list, which can contain
+// extra statements if the init statements of any long if statements were hoisted out of
+// them. The scope describes the function that the statements are in, if any.
+func (s *Shortener) formatStmtList(stmts []dst.Stmt, scope *funcScope) []dst.Stmt {
+	formatted := make([]dst.Stmt, 0, len(stmts))
+	tracker := &directiveTracker{}
+
+	for i, stmt := range stmts {
+		if !tracker.skip(stmt) {
+			ifStmt, ok := stmt.(*dst.IfStmt)
+			if ok && s.config.HoistIfInit && HasAnnotation(ifStmt) &&
+				canHoistIfInit(ifStmt, stmts, i, scope) {
+				formatted = append(formatted, hoistIfInit(ifStmt))
+			}
+
+			s.formatStmt(stmt, false, scope)
+		}
+
+		formatted = append(formatted, stmt)
+	}
+
+	return formatted
+}
+
+// formatFuncBody formats the body of a function declaration or literal. The names of the
+// receiver, params, and results are in scope for all of the statements in the body, which
+// matters when hoisting if statement init statements.
+func (s *Shortener) formatFuncBody(
+	recv *dst.FieldList,
+	funcType *dst.FuncType,
+	body *dst.BlockStmt,
+) {
+	if body == nil {
+		return
+	}
+
+	scope := &funcScope{names: map[string]bool{}}
+	for _, fieldList := range []*dst.FieldList{recv, funcType.Params, funcType.Results} {
+		if fieldList == nil {
+			continue
+		}
+
+		for _, field := range fieldList.List {
+			for _, name := range field.Names {
+				scope.names[name.Name] = true
+				scope.namedResults = scope.namedResults || fieldList == funcType.Results
+			}
+		}
+	}
+
+	body.List = s.formatStmtList(body.List, scope)
+}
+
+// formatExpr formats an AST expression node. These include uniary and binary expressions, function
+// literals, and key/value pair statements, among others.
+func (s *Shortener) formatExpr(expr dst.Expr, force bool, isChain bool) {
+	shouldShorten := force || HasAnnotation(expr)
+
+	switch e := expr.(type) {
+	case *dst.BinaryExpr:
+		if shouldShorten {
+			// The operator at the root of a binary expression has the lowest precedence (or
+			// is the last of several with the same precedence), so break after it, keeping it
+			// at the end of the line as gofmt requires. Except for boolean conditions, which
+			// read best with one per line, if the last operand is a call or composite literal
+			// that hasn't been split yet, split that first instead; the line is only broken
+			// at the operator if it's still too long after that, in which case the operand
+			// is put back on one line. Once that's done, continue with the operand that's
+			// still on the first line; the ones after it are only shortened if their own
+			// lines are too long.
+			isCondition := e.Op == token.LAND || e.Op == token.LOR
+
+			if e.Y.Decorations().Before == dst.NewLine {
+				s.formatExpr(e.X, shouldShorten, isChain)
+				s.formatExpr(e.Y, false, isChain)
+			} else if !isCondition && hasUnsplitList(e.Y) {
+				s.formatExpr(e.X, false, isChain)
+				s.formatExpr(e.Y, shouldShorten, isChain)
+			} else {
+				e.Y.Decorations().Before = dst.NewLine
+				if !isCondition {
+					joinList(e.Y)
+				}
+			}
+		} else {
+			s.formatExpr(e.X, false, isChain)
+			s.formatExpr(e.Y, false, isChain)
+		}
+	case *dst.CallExpr:
+		_, ok := e.Fun.(*dst.SelectorExpr)
+
+		if ok &&
+			s.config.ChainSplitDots &&
+			(shouldShorten || HasAnnotationRecursive(e)) &&
+			(isChain || s.chainLength(e) > 1) {
+			e.Decorations().After = dst.NewLine
+
+			for _, arg := range e.Args {
+				s.formatExpr(arg, false, true)
+			}
+
+			s.formatExpr(e.Fun, shouldShorten, true)
+		} else {
+			shortenChildArgs := shouldShorten || HasAnnotationRecursive(e)
+			argsSplit := len(e.Args) == 0 || e.Args[0].Decorations().Before == dst.NewLine
+
+			for a, arg := range e.Args {
+				if shortenChildArgs {
+					if a == 0 {
+						arg.Decorations().Before = dst.NewLine
+					} else {
+						arg.Decorations().After = dst.None
+					}
+					arg.Decorations().After = dst.NewLine
+				}
+				s.formatExpr(arg, false, isChain)
+			}
+
+			if _, ok := e.Fun.(*dst.IndexListExpr); ok {
+				// Only split the type args if splitting the args wasn't enough
+				s.formatExpr(e.Fun, shortenChildArgs && argsSplit, isChain)
+			} else {
+				s.formatExpr(e.Fun, shouldShorten, isChain)
+			}
+		}
+	case *dst.CompositeLit:
+		if shouldShorten {
+			for i, element := range e.Elts {
+				if i == 0 {
+					element.Decorations().Before = dst.NewLine
+				}
+				element.Decorations().After = dst.NewLine
+			}
+		}
+
+		for _, element := range e.Elts {
+			s.formatExpr(element, false, isChain)
+		}
+	case *dst.FuncLit:
+		s.formatFuncBody(nil, e.Type, e.Body)
+	case *dst.FuncType:
+		if shouldShorten {
+			s.formatFieldList(e.Params)
+		}
+	case *dst.IndexExpr:
+		s.formatOperands([]dst.Expr{e.X, e.Index}, shouldShorten, isChain)
+	case *dst.IndexListExpr:
+		if shouldShorten {
+			for i, index := range e.Indices {
+				if i == 0 {
+					index.Decorations().Before = dst.NewLine
+				} else {
+					index.Decorations().Before = dst.None
+				}
+				index.Decorations().After = dst.NewLine
+			}
+		}
+
+		s.formatExpr(e.X, false, isChain)
+		for _, index := range e.Indices {
+			s.formatExpr(index, false, isChain)
+		}
+	case *dst.InterfaceType:
+		for _, method := range e.Methods.List {
+			if !HasAnnotation(method) {
+				continue
+			}
+
+			if union, ok := method.Type.(*dst.BinaryExpr); ok && union.Op == token.OR {
+				// Union of types in a constraint
+				s.formatUnion(union)
+			} else {
+				s.formatExpr(method.Type, true, isChain)
+			}
+		}
+	case *dst.KeyValueExpr:
+		s.formatExpr(e.Value, shouldShorten, isChain)
+	case *dst.ParenExpr:
+		s.formatExpr(e.X, shouldShorten, isChain)
+	case *dst.SelectorExpr:
+		s.formatExpr(e.X, shouldShorten, isChain)
+	case *dst.SliceExpr:
+		s.formatOperands([]dst.Expr{e.X, e.Low, e.High, e.Max}, shouldShorten, isChain)
+	case *dst.StarExpr:
+		s.formatExpr(e.X, shouldShorten, isChain)
+	case *dst.StructType:
+		if s.config.ReformatTags {
+			FormatStructTags(e.Fields)
+		}
+	case *dst.TypeAssertExpr:
+		s.formatExpr(e.X, shouldShorten, isChain)
+		if e.Type != nil {
+			// Type is nil for the ".(type)" in type switches
+			s.formatExpr(e.Type, false, isChain)
+		}
+	case *dst.UnaryExpr:
+		s.formatExpr(e.X, shouldShorten, isChain)
+	default:
+		if shouldShorten {
+			log.Debugf(
+				"got an expression type that can't be shortened: %+v",
+				reflect.TypeOf(e),
+			)
+		}
+	}
+}
+
+// formatOperands formats the operands of an index or slice expression or a send statement,
+// skipping any that are nil. If force is true, only the first operand that can be split is
+// shortened, since that's usually enough to get the line under the limit.
+func (s *Shortener) formatOperands(exprs []dst.Expr, force bool, isChain bool) {
+	forced := false
+
+	for _, expr := range exprs {
+		if expr == nil {
+			continue
+		}
+
+		shouldShorten := force && !forced && isSplittable(expr)
+		forced = forced || shouldShorten
+		s.formatExpr(expr, shouldShorten, isChain)
+	}
+}
+
+// formatSpec formats an AST spec node. These include type specifications, among other things.
+func (s *Shortener) formatSpec(spec dst.Spec, force bool) {
+	shouldShorten := HasAnnotation(spec) || force
+	switch sp := spec.(type) {
+	case *dst.ValueSpec:
+		for _, expr := range sp.Values {
+			s.formatExpr(expr, shouldShorten, false)
+		}
+	case *dst.TypeSpec:
+		if !s.formatTypeParamConstraints(sp.TypeParams) && shouldShorten {
+			s.formatFieldList(sp.TypeParams)
+		}
+		s.formatExpr(sp.Type, false, false)
+	default:
+		if shouldShorten {
+			log.Debugf(
+				"got a spec type that can't be shortened: %+v",
+				reflect.TypeOf(sp),
+			)
+		}
+	}
+}
+
+// IsGenerated checks whether the provided file bytes are from a generated file.
+// This is done by looking for a set of typically-used strings in the first 5 lines.
+func IsGenerated(contents []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewBuffer(contents))
+
+	for i := 0; scanner.Scan(); i++ {
+		if i >= 5 {
+			return false
+		}
+
+		for _, term := range generatedTerms {
+			if strings.Contains(strings.ToLower(scanner.Text()), term) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isSplittable determines whether the provided node contains anything that can be split over
+// multiple lines, i.e. a call with args or a composite literal with elements.
+func isSplittable(node dst.Node) bool {
+	splittable := false
+
+	dst.Inspect(
+		node,
+		func(n dst.Node) bool {
+			switch e := n.(type) {
+			case *dst.CallExpr:
+				splittable = len(e.Args) > 0
+			case *dst.CompositeLit:
+				splittable = len(e.Elts) > 0
+			}
+			return !splittable
+		},
+	)
+
+	return splittable
+}
+
+// hasUnsplitList determines whether the provided expression is a call with arguments or a
+// composite literal with elements that aren't on separate lines yet.
+func hasUnsplitList(expr dst.Expr) bool {
+	switch e := expr.(type) {
+	case *dst.CallExpr:
+		return len(e.Args) > 0 && e.Args[0].Decorations().Before != dst.NewLine
+	case *dst.CompositeLit:
+		return len(e.Elts) > 0 && e.Elts[0].Decorations().Before != dst.NewLine
+	default:
+		return false
+	}
+}
+
+// joinList puts the arguments of a call or the elements of a composite literal back on the
+// same line as the opening parenthesis or brace. Lists with comments are left alone.
+func joinList(expr dst.Expr) {
+	var elements []dst.Expr
+
+	switch e := expr.(type) {
+	case *dst.CallExpr:
+		elements = e.Args
+	case *dst.CompositeLit:
+		elements = e.Elts
+	default:
+		return
+	}
+
+	for _, element := range elements {
+		decorations := element.Decorations()
+		if len(decorations.Start) > 0 || len(decorations.End) > 0 {
+			return
+		}
+	}
+
+	for _, element := range elements {
+		element.Decorations().Before = dst.None
+		element.Decorations().After = dst.None
+	}
+}
+
+// chainLength determines the length of the function call chain in an expression.
+func (s *Shortener) chainLength(callExpr *dst.CallExpr) int {
+	numCalls := 1
+	currCall := callExpr
+
+	for {
+		selectorExpr, ok := currCall.Fun.(*dst.SelectorExpr)
+		if !ok {
+			break
+		}
+		currCall, ok = selectorExpr.X.(*dst.CallExpr)
+		if !ok {
+			break
+		}
+		numCalls++
+	}
+
+	return numCalls
+}
@@ -0,0 +1,485 @@
+package shorten
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fixturesDir = "_fixtures"
+
+// TestShortener verifies the core shortening functionality on the files in the _fixtures
+// directory. To update the expected outputs, run tests with the REGENERATE_TEST_OUTPUTS
+// environment variable set to "true".
+func TestShortener(t *testing.T) {
+	info, err := os.ReadDir(fixturesDir)
+	assert.Nil(t, err)
+
+	fixturePaths := []string{}
+
+	for _, fileInfo := range info {
+		if fileInfo.IsDir() {
+			continue
+		} else if !strings.HasSuffix(fileInfo.Name(), ".go") {
+			continue
+		} else if strings.HasSuffix(fileInfo.Name(), "__exp.go") {
+			continue
+		}
+
+		fixturePaths = append(
+			fixturePaths,
+			filepath.Join(fixturesDir, fileInfo.Name()),
+		)
+	}
+
+	dotDir, err := os.MkdirTemp("", "dot")
+	if err != nil {
+		t.Fatalf("Error creating output directory for dot files: %+v", err)
+	}
+	defer os.RemoveAll(dotDir)
+
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			KeepAnnotations:  false,
+			ShortenComments:  true,
+			ReformatTags:     true,
+			IgnoreGenerated:  true,
+			BaseFormatterCmd: "gofmt",
+			DotFile:          filepath.Join(dotDir, "out.dot"),
+			ChainSplitDots:   true,
+		},
+	)
+
+	for _, fixturePath := range fixturePaths {
+		contents, err := os.ReadFile(fixturePath)
+		if err != nil {
+			t.Fatalf(
+				"Unexpected error reading fixture %s: %+v",
+				fixturePath,
+				err,
+			)
+		}
+
+		shortenedContents, err := shortener.Shorten(contents)
+		assert.Nil(t, err)
+
+		expectedPath := fixturePath[0:len(fixturePath)-3] + "__exp" + ".go"
+
+		if os.Getenv("REGENERATE_TEST_OUTPUTS") == "true" {
+			err := os.WriteFile(expectedPath, shortenedContents, 0644)
+			if err != nil {
+				t.Fatalf(
+					"Unexpected error writing output file %s: %+v",
+					expectedPath,
+					err,
+				)
+			}
+		}
+
+		expectedContents, err := os.ReadFile(expectedPath)
+		if err != nil {
+			t.Fatalf(
+				"Unexpected error reading expected file %s: %+v",
+				expectedPath,
+				err,
+			)
+		}
+
+		assert.Equal(t, string(expectedContents), string(shortenedContents))
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen: 30,
+			TabLen: 4,
+		},
+	)
+
+	contents := []byte(`package main
+
+func main() {
+	x := "a long string literal"
+	//golines:ignore
+	y := "another long string literal"
+	//golines:max-len=40
+	z := "a slightly longer string literal"
+}
+`)
+
+	assert.Equal(
+		t,
+		[]LongLine{
+			{Line: 4, Column: 28, Length: 32, MaxLen: 30},
+			{Line: 8, Column: 38, Length: 43, MaxLen: 40},
+		},
+		shortener.LongLines(contents),
+	)
+}
+
+func TestShortenerGofumpt(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:             100,
+			TabLen:             4,
+			BaseFormatterCmd:   "gofumpt",
+			GofumptLangVersion: "go1.22",
+		},
+	)
+
+	contents := []byte(`package main
+
+import "fmt"
+
+func main() {
+
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	result, err := shortener.Shorten(contents)
+	assert.Nil(t, err)
+
+	// gofumpt removes the empty line at the start of the function body
+	assert.Equal(
+		t,
+		`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf(
+		"%s %s %s %s %s %s",
+		"argument1",
+		"argument2",
+		"argument3",
+		"argument4",
+		"argument5",
+		"argument6",
+	)
+}
+`,
+		string(result),
+	)
+}
+
+func TestShortenerGoimports(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:        100,
+			TabLen:        4,
+			LocalPrefixes: "github.com/segmentio",
+		},
+	)
+
+	contents := []byte(`package main
+
+import (
+	"github.com/segmentio/golines/shorten"
+	"github.com/stretchr/testify/assert"
+)
+
+func main() {
+	fmt.Println(shorten.LineRange{}, assert.Equal)
+}
+`)
+
+	result, err := shortener.WithFilename("main.go").Shorten(contents)
+	assert.Nil(t, err)
+
+	// The missing fmt import is added, and the local imports are in a separate group
+	assert.Equal(
+		t,
+		`package main
+
+import (
+	"fmt"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/segmentio/golines/shorten"
+)
+
+func main() {
+	fmt.Println(shorten.LineRange{}, assert.Equal)
+}
+`,
+		string(result),
+	)
+}
+
+func TestShortenerBaseFormatterCmd(t *testing.T) {
+	contents := []byte("package main\n")
+
+	// Arguments are split using shell-style quoting, and {{path}} is replaced with the path
+	// of the file
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: `sed "s|^package main$|package main // {{path}}|"`,
+		},
+	)
+	result, err := shortener.WithFilename("dir/file.go").Shorten(contents)
+	assert.Nil(t, err)
+	assert.Equal(t, "package main // dir/file.go\n", string(result))
+
+	// The built-in formatters are run as subprocesses if there are arguments for them
+	shortener = NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt -s",
+		},
+	)
+	result, err = shortener.Shorten([]byte("package main\n\nvar x = []T{T{1}}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "package main\n\nvar x = []T{{1}}\n", string(result))
+
+	// The stderr output is included in errors
+	shortener = NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: `sh -c "echo 'something went wrong' >&2; exit 1"`,
+		},
+	)
+	_, err = shortener.Shorten(contents)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "exit status 1: something went wrong")
+	}
+
+	// Slow formatters time out
+	shortener = NewShortener(
+		ShortenerConfig{
+			MaxLen:               100,
+			TabLen:               4,
+			BaseFormatterCmd:     "sleep 10",
+			BaseFormatterTimeout: 50 * time.Millisecond,
+		},
+	)
+	_, err = shortener.Shorten(contents)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "timed out after 50ms")
+	}
+
+	// Invalid commands are reported when the formatter is run
+	shortener = NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: `gofmt "unterminated`,
+		},
+	)
+	_, err = shortener.Shorten(contents)
+	assert.NotNil(t, err)
+}
+
+func TestShortenContext(t *testing.T) {
+	contents := []byte(`package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%s %s %s %s %s %s", "argument1", "argument2", "argument3", "argument4", "argument5", "argument6")
+}
+`)
+
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+		},
+	)
+
+	result, err := shortener.ShortenContext(context.Background(), contents)
+	assert.Nil(t, err)
+	assert.NotEqual(t, string(contents), string(result))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = shortener.ShortenContext(ctx, contents)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The base formatter subprocess is killed when the context is done
+	shortener = NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "sleep 10",
+		},
+	)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = shortener.ShortenContext(ctx, contents)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestShortenerHoistIfInit(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           100,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+			HoistIfInit:      true,
+		},
+	)
+
+	contents := []byte(`package main
+
+func hoisted() {
+	// Comment about the call
+	if result, err := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, third); err != nil {
+		return
+	}
+}
+
+func usedLater() {
+	if result, err := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, third); err != nil {
+		return
+	}
+
+	err := client.DoSomethingElse()
+}
+
+func namedResult() (err error) {
+	if result, err := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, third); err != nil {
+		return
+	}
+}
+
+func nestedNamedResult(items []string) (err error) {
+	for _, item := range items {
+		if err := client.DoSomethingVeryLong(ctx, item, "a long argument", "another long argument"); err != nil {
+			continue
+		}
+		if item == "" {
+			return
+		}
+	}
+	return nil
+}
+
+func nestedBareReturn(items []string) (count int) {
+	for _, item := range items {
+		if res := client.DoSomethingVeryLong(ctx, item, "a long argument", "another long argument"); res {
+			continue
+		}
+		if item == "" {
+			return
+		}
+	}
+	return 0
+}
+
+func nestedHoisted(items []string) {
+	for _, item := range items {
+		if err := client.DoSomethingVeryLong(ctx, item, "a long argument", "another long argument"); err != nil {
+			continue
+		}
+	}
+}
+`)
+
+	result, err := shortener.Shorten(contents)
+	assert.Nil(t, err)
+
+	// The init statement is only hoisted if the variables that it declares aren't used
+	// anywhere else in the same scope, including by bare returns, and don't shadow any of the
+	// function's params or results
+	assert.Equal(
+		t,
+		`package main
+
+func hoisted() {
+	// Comment about the call
+	result, err := client.DoSomethingVeryLong(ctx, firstArgument, secondArgument, third)
+	if err != nil {
+		return
+	}
+}
+
+func usedLater() {
+	if result, err := client.DoSomethingVeryLong(
+		ctx,
+		firstArgument,
+		secondArgument,
+		third,
+	); err != nil {
+		return
+	}
+
+	err := client.DoSomethingElse()
+}
+
+func namedResult() (err error) {
+	if result, err := client.DoSomethingVeryLong(
+		ctx,
+		firstArgument,
+		secondArgument,
+		third,
+	); err != nil {
+		return
+	}
+}
+
+func nestedNamedResult(items []string) (err error) {
+	for _, item := range items {
+		if err := client.DoSomethingVeryLong(
+			ctx,
+			item,
+			"a long argument",
+			"another long argument",
+		); err != nil {
+			continue
+		}
+		if item == "" {
+			return
+		}
+	}
+	return nil
+}
+
+func nestedBareReturn(items []string) (count int) {
+	for _, item := range items {
+		if res := client.DoSomethingVeryLong(
+			ctx,
+			item,
+			"a long argument",
+			"another long argument",
+		); res {
+			continue
+		}
+		if item == "" {
+			return
+		}
+	}
+	return 0
+}
+
+func nestedHoisted(items []string) {
+	for _, item := range items {
+		err := client.DoSomethingVeryLong(ctx, item, "a long argument", "another long argument")
+		if err != nil {
+			continue
+		}
+	}
+}
+`,
+		string(result),
+	)
+}
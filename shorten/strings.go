@@ -0,0 +1,233 @@
+package shorten
+
+import (
+	"bytes"
+	"go/token"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/dstutil"
+	log "github.com/sirupsen/logrus"
+)
+
+// Minimum number of characters in each part of a split string; literals that would need to be
+// split into smaller parts than this are left alone
+const minStringPartLen = 10
+
+// splitStringsFunc splits interpreted string literals that extend past the end of long lines
+// into concatenations of shorter literals, breaking them at word boundaries. This runs after
+// the other shortening strategies, so it only applies to lines that couldn't be shortened
+// otherwise. If filter is non-nil, only the lines that it matches are considered.
+func (s *Shortener) splitStringsFunc(contents []byte, filter *lineFilter) ([]byte, error) {
+	for round := 0; round < maxRounds; round++ {
+		lines := strings.Split(string(contents), "\n")
+		selected := filter.matches(lines)
+		ignored, maxLens := lineDirectives(lines, s.config.MaxLen)
+
+		dec := decorator.NewDecorator(token.NewFileSet())
+		file, err := dec.Parse(contents)
+		if err != nil {
+			return nil, err
+		}
+
+		// Lines that already had a literal split in this round; the positions of any other
+		// literals on these lines are out of date until the next round
+		splitLines := map[int]bool{}
+
+		dstutil.Apply(
+			file,
+			nil,
+			func(c *dstutil.Cursor) bool {
+				lit, ok := c.Node().(*dst.BasicLit)
+				if !ok || lit.Kind != token.STRING || !strings.HasPrefix(lit.Value, `"`) {
+					return true
+				}
+
+				switch c.Parent().(type) {
+				case *dst.Field, *dst.ImportSpec:
+					// Struct tags and import paths need to be single literals
+					return true
+				}
+
+				astNode, ok := dec.Ast.Nodes[lit]
+				if !ok {
+					return true
+				}
+				position := dec.Fset.Position(astNode.Pos())
+				l := position.Line - 1
+
+				if l < 0 || l >= len(lines) || !selected[l] || ignored[l] || splitLines[l] {
+					return true
+				}
+
+				line := lines[l]
+				start := position.Column - 1
+				end := start + len(lit.Value)
+				if end > len(line) || s.lineLen(line[:end]) <= maxLens[l] {
+					// Literal spans multiple lines or doesn't extend past the end of the line
+					return true
+				}
+
+				// The first part continues the current line, and the remaining ones are
+				// indented one level further; each part needs room for the quotes and the
+				// trailing " +"
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				firstLen := maxLens[l] - s.lineLen(line[:start]) - 4
+				restLen := maxLens[l] - s.lineLen(indent) - s.config.TabLen - 4
+
+				parts := splitStringValue(lit.Value, firstLen, restLen)
+				if len(parts) < 2 {
+					return true
+				}
+
+				log.Debugf("splitting string literal on line %d into %d parts", l+1, len(parts))
+				c.Replace(concatExpr(lit, parts))
+				splitLines[l] = true
+
+				return true
+			},
+		)
+
+		if len(splitLines) == 0 {
+			break
+		}
+
+		output := bytes.NewBuffer([]byte{})
+		err = decorator.Fprint(output, file)
+		if err != nil {
+			return nil, err
+		}
+		contents = output.Bytes()
+	}
+
+	return contents, nil
+}
+
+// concatExpr creates a binary expression that concatenates the provided string literal
+// values, with each one after the first on a new line. The decorations of the original
+// literal are moved to the resulting expression.
+func concatExpr(lit *dst.BasicLit, values []string) dst.Expr {
+	var expr dst.Expr = &dst.BasicLit{Kind: token.STRING, Value: values[0]}
+
+	for _, value := range values[1:] {
+		part := &dst.BasicLit{Kind: token.STRING, Value: value}
+		part.Decs.Before = dst.NewLine
+
+		expr = &dst.BinaryExpr{X: expr, Op: token.ADD, Y: part}
+	}
+
+	*expr.Decorations() = lit.Decs.NodeDecs
+
+	return expr
+}
+
+// splitStringValue splits the value of an interpreted string literal, including its quotes,
+// into multiple literal values. Splits are only made after spaces, and never in the middle
+// of escape sequences or format verbs. The first part has room for firstLen characters
+// between its quotes and the rest have room for restLen; parts can be longer if there are no
+// spaces to split on. It returns a single value if the literal can't be split.
+func splitStringValue(value string, firstLen int, restLen int) []string {
+	if firstLen < minStringPartLen || restLen < minStringPartLen {
+		return []string{value}
+	}
+
+	tokens := stringTokens(value[1 : len(value)-1])
+	parts := []string{}
+
+	// Start of the current part and the end of its last word, as indices into tokens
+	start := 0
+	lastBreak := -1
+	partLen := 0
+
+	for t, tok := range tokens {
+		maxPartLen := restLen
+		if len(parts) == 0 {
+			maxPartLen = firstLen
+		}
+
+		partLen += utf8.RuneCountInString(tok)
+		if partLen > maxPartLen && lastBreak > start {
+			parts = append(parts, strings.Join(tokens[start:lastBreak], ""))
+
+			start = lastBreak
+			partLen = 0
+			for _, partTok := range tokens[start : t+1] {
+				partLen += utf8.RuneCountInString(partTok)
+			}
+		}
+
+		if tok == " " {
+			lastBreak = t + 1
+		}
+	}
+	parts = append(parts, strings.Join(tokens[start:], ""))
+
+	values := []string{}
+	for _, part := range parts {
+		values = append(values, `"`+part+`"`)
+	}
+	return values
+}
+
+// stringTokens splits the contents of an interpreted string literal into the smallest
+// units that can't be broken up: escape sequences, format verbs, and single characters.
+func stringTokens(contents string) []string {
+	tokens := []string{}
+
+	for i := 0; i < len(contents); {
+		var n int
+
+		switch contents[i] {
+		case '\\':
+			n = escapeLen(contents[i:])
+		case '%':
+			n = verbLen(contents[i:])
+		default:
+			_, n = utf8.DecodeRuneInString(contents[i:])
+		}
+
+		tokens = append(tokens, contents[i:i+n])
+		i += n
+	}
+
+	return tokens
+}
+
+// escapeLen returns the length of the escape sequence at the start of the provided string,
+// e.g. 2 for "\n" or 4 for "\x41".
+func escapeLen(value string) int {
+	n := 2
+
+	if len(value) > 1 {
+		switch value[1] {
+		case 'x':
+			n = 4
+		case 'u':
+			n = 6
+		case 'U':
+			n = 10
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			n = 4
+		}
+	}
+
+	return min(n, len(value))
+}
+
+// verbLen returns the length of the fmt-style format verb at the start of the provided
+// string, including any flags, argument indices, width, and precision, e.g. 3 for "%+v".
+func verbLen(value string) int {
+	n := 1
+	for n < len(value) && strings.IndexByte("+-# 0123456789.*[]", value[n]) >= 0 {
+		n++
+	}
+
+	if n < len(value) {
+		_, verbSize := utf8.DecodeRuneInString(value[n:])
+		n += verbSize
+	}
+
+	return n
+}
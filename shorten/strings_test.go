@@ -0,0 +1,130 @@
+package shorten
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStringValue(t *testing.T) {
+	type testCase struct {
+		value    string
+		firstLen int
+		restLen  int
+		expected []string
+	}
+
+	testCases := []testCase{
+		{
+			value:    `"short"`,
+			firstLen: 20,
+			restLen:  20,
+			expected: []string{`"short"`},
+		},
+		{
+			value:    `"the quick brown fox jumps over the lazy dog"`,
+			firstLen: 15,
+			restLen:  20,
+			expected: []string{`"the quick "`, `"brown fox jumps "`, `"over the lazy dog"`},
+		},
+		{
+			// Format verbs aren't split, even if they contain spaces
+			value:    `"the value was % d and %+v"`,
+			firstLen: 15,
+			restLen:  15,
+			expected: []string{`"the value was "`, `"% d and %+v"`},
+		},
+		{
+			// Escape sequences aren't split
+			value:    `"tab\tseparated\x41 words"`,
+			firstLen: 10,
+			restLen:  10,
+			expected: []string{`"tab\tseparated\x41 "`, `"words"`},
+		},
+		{
+			// Escape sequences count towards the length with their full width in the source,
+			// not as the single character that they represent
+			value:    `"aaaaaa \x41\x41 bb"`,
+			firstLen: 12,
+			restLen:  12,
+			expected: []string{`"aaaaaa "`, `"\x41\x41 bb"`},
+		},
+		{
+			value:    `"averyveryverylongword"`,
+			firstLen: 10,
+			restLen:  10,
+			expected: []string{`"averyveryverylongword"`},
+		},
+		{
+			// Parts can't be shorter than the minimum length
+			value:    `"the quick brown fox jumps over the lazy dog"`,
+			firstLen: 5,
+			restLen:  20,
+			expected: []string{`"the quick brown fox jumps over the lazy dog"`},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.expected,
+			splitStringValue(testCase.value, testCase.firstLen, testCase.restLen),
+			testCase.value,
+		)
+	}
+}
+
+func TestShortenerSplitStrings(t *testing.T) {
+	shortener := NewShortener(
+		ShortenerConfig{
+			MaxLen:           60,
+			TabLen:           4,
+			BaseFormatterCmd: "gofmt",
+			SplitStrings:     true,
+		},
+	)
+
+	contents := []byte(`package main
+
+import "fmt"
+
+type T struct {
+	Field string "a long struct tag that can't be split since it needs to be a literal"
+}
+
+func main() {
+	x := "a long string literal that goes past the end of the line"
+	fmt.Printf("the request failed after %d attempts with status %+v", 3, "x")
+	//golines:ignore
+	y := "another long string literal that goes past the end of the line"
+}
+`)
+
+	result, err := shortener.Shorten(contents)
+	assert.Nil(t, err)
+	assert.Equal(
+		t,
+		`package main
+
+import "fmt"
+
+type T struct {
+	Field string "a long struct tag that can't be split since it needs to be a literal"
+}
+
+func main() {
+	x := "a long string literal that goes past the end " +
+		"of the line"
+	fmt.Printf(
+		"the request failed after %d attempts with "+
+			"status %+v",
+		3,
+		"x",
+	)
+	//golines:ignore
+	y := "another long string literal that goes past the end of the line"
+}
+`,
+		string(result),
+	)
+}